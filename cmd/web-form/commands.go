@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/reddec/web-form/internal/codes"
+	"github.com/reddec/web-form/internal/deliveries"
+	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/signed"
+	"github.com/reddec/web-form/internal/storage"
+
+	"github.com/jessevdk/go-flags"
+)
+
+func addCommands(parser *flags.Parser, config *Config) {
+	_, _ = parser.AddCommand("deliveries", "Show delivery log",
+		"Show notifications delivery attempts for submission. Requires database storage and database delivery log.",
+		&DeliveriesCommand{config: config})
+
+	codesCmd, _ := parser.AddCommand("codes", "Manage issued access codes",
+		"Mint, revoke and list issued access codes for forms with issued_codes. Requires database storage.",
+		&struct{}{})
+	_, _ = codesCmd.AddCommand("mint", "Mint new codes",
+		"Generate new access codes and print them as JSON lines.",
+		&CodesMintCommand{config: config})
+	_, _ = codesCmd.AddCommand("revoke", "Revoke codes",
+		"Revoke listed access codes or all codes of the form.",
+		&CodesRevokeCommand{config: config})
+	_, _ = codesCmd.AddCommand("list", "List codes",
+		"Print all access codes of the form as JSON lines.",
+		&CodesListCommand{config: config})
+
+	_, _ = parser.AddCommand("sign", "Sign prefill link",
+		"Create prefill link signed by the links key. Values are passed as name=value arguments.",
+		&SignCommand{config: config})
+
+	_, _ = parser.AddCommand("hash", "Hash access code",
+		"Hash access code to use in form definition instead of plain code. If code is not set, it will be read from stdin.",
+		&HashCommand{})
+}
+
+type DeliveriesCommand struct {
+	config *Config
+	Args   struct {
+		Submission string `positional-arg-name:"submission" description:"Submission ID" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (cmd *DeliveriesCommand) Execute([]string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	db, err := storage.NewDB(ctx, cmd.config.DB.Dialect, cmd.config.DB.URL)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	log, err := deliveries.NewDB(ctx, db.DB())
+	if err != nil {
+		return fmt.Errorf("open delivery log: %w", err)
+	}
+
+	list, err := log.List(ctx, cmd.Args.Submission)
+	if err != nil {
+		return fmt.Errorf("list attempts: %w", err)
+	}
+
+	return printJSONLines(list)
+}
+
+type CodesMintCommand struct {
+	config  *Config
+	Form    string        `long:"form" short:"f" description:"Form name" required:"yes"`
+	Count   int           `long:"count" short:"n" description:"Number of codes. Ignored if values set" default:"1"`
+	TTL     time.Duration `long:"ttl" description:"Code lifetime. Codes never expire if not set"`
+	MaxUses int           `long:"max-uses" description:"Maximum number of submissions per code, 0 means unlimited" default:"1"`
+	Values  string        `long:"values" description:"CSV file with values bound to codes: header is field names, one code per row"`
+}
+
+func (cmd *CodesMintCommand) Execute([]string) error {
+	mint := codes.Mint{
+		Count:   cmd.Count,
+		MaxUses: cmd.MaxUses,
+	}
+	if cmd.TTL > 0 {
+		expiresAt := time.Now().Add(cmd.TTL)
+		mint.ExpiresAt = &expiresAt
+	}
+	if cmd.Values != "" {
+		values, err := readValues(cmd.Values)
+		if err != nil {
+			return fmt.Errorf("read values: %w", err)
+		}
+		mint.Values = values
+	}
+	if err := mint.Validate(time.Now()); err != nil {
+		return err
+	}
+
+	return withCodes(cmd.config, func(ctx context.Context, store *codes.DB) error {
+		list, err := store.Mint(ctx, cmd.Form, mint)
+		if err != nil {
+			return fmt.Errorf("mint codes: %w", err)
+		}
+		return printJSONLines(list)
+	})
+}
+
+type CodesRevokeCommand struct {
+	config *Config
+	Form   string `long:"form" short:"f" description:"Form name" required:"yes"`
+	All    bool   `long:"all" description:"Revoke all codes of the form"`
+	Args   struct {
+		Codes []string `positional-arg-name:"code" description:"Codes (or their digests) to revoke"`
+	} `positional-args:"yes"`
+}
+
+func (cmd *CodesRevokeCommand) Execute([]string) error {
+	if !cmd.All && len(cmd.Args.Codes) == 0 {
+		return errors.New("codes or --all required")
+	}
+	return withCodes(cmd.config, func(ctx context.Context, store *codes.DB) error {
+		var (
+			revoked int
+			err     error
+		)
+		if cmd.All {
+			revoked, err = store.RevokeAll(ctx, cmd.Form)
+		} else {
+			revoked, err = store.Revoke(ctx, cmd.Form, cmd.Args.Codes)
+		}
+		if err != nil {
+			return fmt.Errorf("revoke codes: %w", err)
+		}
+		_, _ = fmt.Fprintln(os.Stderr, "revoked:", revoked)
+		return nil
+	})
+}
+
+type CodesListCommand struct {
+	config *Config
+	Form   string `long:"form" short:"f" description:"Form name" required:"yes"`
+}
+
+func (cmd *CodesListCommand) Execute([]string) error {
+	return withCodes(cmd.config, func(ctx context.Context, store *codes.DB) error {
+		list, err := store.List(ctx, cmd.Form)
+		if err != nil {
+			return fmt.Errorf("list codes: %w", err)
+		}
+		return printJSONLines(list)
+	})
+}
+
+func withCodes(config *Config, handler func(ctx context.Context, store *codes.DB) error) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	db, err := storage.NewDB(ctx, config.DB.Dialect, config.DB.URL)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	store, err := codes.NewDB(ctx, db.DB())
+	if err != nil {
+		return fmt.Errorf("open codes: %w", err)
+	}
+	return handler(ctx, store)
+}
+
+// readValues reads CSV file where first row is header with field names and each next row is values for one code.
+func readValues(file string) ([]map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	var ans []map[string]string
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]string, len(header))
+		for i, name := range header {
+			values[name] = row[i]
+		}
+		ans = append(ans, values)
+	}
+	return ans, nil
+}
+
+func printJSONLines[T any](list []T) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, item := range list {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type SignCommand struct {
+	config *Config
+	Form   string        `long:"form" short:"f" description:"Form name" required:"yes"`
+	TTL    time.Duration `long:"ttl" description:"Link lifetime. Default is links TTL"`
+	Args   struct {
+		Values []string `positional-arg-name:"name=value" description:"Prefill values"`
+	} `positional-args:"yes"`
+}
+
+func (cmd *SignCommand) Execute([]string) error {
+	if cmd.config.Links.Key == "" {
+		return errors.New("links key is not set")
+	}
+	signer, err := signed.New([]byte(cmd.config.Links.Key))
+	if err != nil {
+		return err
+	}
+
+	var values = make(url.Values, len(cmd.Args.Values))
+	for _, arg := range cmd.Args.Values {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("invalid value %q: expected name=value", arg)
+		}
+		values.Add(name, value)
+	}
+
+	ttl := cmd.TTL
+	if ttl <= 0 {
+		ttl = cmd.config.Links.TTL
+	}
+	_, err = fmt.Println(signed.URL(cmd.config.ServerURL, cmd.Form, signer.Sign(cmd.Form, values, time.Now().Add(ttl))))
+	return err
+}
+
+type HashCommand struct {
+	Algorithm string `long:"algorithm" short:"a" description:"Hash algorithm" default:"bcrypt" choice:"bcrypt" choice:"argon2id"`
+	Args      struct {
+		Code string `positional-arg-name:"code" description:"Access code. If not set - read from stdin"`
+	} `positional-args:"yes"`
+}
+
+func (cmd *HashCommand) Execute([]string) error {
+	code := cmd.Args.Code
+	if code == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("read code: %w", err)
+		}
+		code = strings.TrimRight(line, "\r\n")
+	}
+	hash, err := schema.HashCode(code, cmd.Algorithm)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(hash)
+	return err
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -11,27 +12,47 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/reddec/web-form/internal/antispam"
 	"github.com/reddec/web-form/internal/assets"
+	"github.com/reddec/web-form/internal/audit"
 	"github.com/reddec/web-form/internal/captcha"
+	"github.com/reddec/web-form/internal/codes"
+	"github.com/reddec/web-form/internal/deliveries"
 	"github.com/reddec/web-form/internal/engine"
+	"github.com/reddec/web-form/internal/forwardauth"
+	"github.com/reddec/web-form/internal/health"
+	"github.com/reddec/web-form/internal/hooks"
+	"github.com/reddec/web-form/internal/htpasswd"
+	"github.com/reddec/web-form/internal/httpclient"
+	"github.com/reddec/web-form/internal/i18n"
+	"github.com/reddec/web-form/internal/limits"
+	"github.com/reddec/web-form/internal/metrics"
 	"github.com/reddec/web-form/internal/notifications/amqp"
 	"github.com/reddec/web-form/internal/notifications/webhook"
+	"github.com/reddec/web-form/internal/oidcauth"
+	"github.com/reddec/web-form/internal/ratelimit"
 	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/signed"
 	"github.com/reddec/web-form/internal/storage"
+	"github.com/reddec/web-form/internal/tracing"
+	"github.com/reddec/web-form/internal/utils"
 	"github.com/reddec/web-form/internal/web"
 
 	_ "modernc.org/sqlite"
 
 	"github.com/alexedwards/scs/redisstore"
 	"github.com/alexedwards/scs/v2"
-	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/go-chi/chi/v5"
 	"github.com/gomodule/redigo/redis"
 	"github.com/hashicorp/go-multierror"
 	"github.com/jessevdk/go-flags"
-	oidclogin "github.com/reddec/oidc-login"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 //nolint:gochecknoglobals
@@ -43,8 +64,12 @@ var (
 )
 
 const (
-	description = "Self-hosted Web Forms"
-	name        = "web-forms"
+	description       = "Self-hosted Web Forms"
+	name              = "web-forms"
+	dropReportTimeout = 5 * time.Second
+	// redis pool for rate limits
+	defaultRedisIdle        = 1
+	defaultRedisConnections = 10
 )
 
 type Config struct {
@@ -68,29 +93,98 @@ type Config struct {
 		Buffer  int    `long:"buffer" env:"BUFFER" description:"Internal queue size before processing" default:"100"`
 		Workers int    `long:"workers" env:"WORKERS" description:"Number of parallel publishers" default:"4"`
 	} `group:"AMQP configuration" namespace:"amqp" env-namespace:"AMQP"`
+	Deliveries struct {
+		Log   string `long:"log" env:"LOG" description:"Where to keep notifications delivery attempts. Database requires database storage" default:"memory" choice:"none" choice:"memory" choice:"database"`
+		Size  int    `long:"size" env:"SIZE" description:"Maximum number of submissions kept by in-memory log" default:"1000"`
+		Token string `long:"token" env:"TOKEN" description:"Bearer token for delivery log API. If not set - API is disabled"`
+	} `group:"Delivery log configuration" namespace:"deliveries" env-namespace:"DELIVERIES"`
+	Audit struct {
+		File     string `long:"file" env:"FILE" description:"Append audit events to the JSONL file"`
+		Database bool   `long:"database" env:"DATABASE" description:"Store audit events in database. Requires database storage"`
+		Webhook  string `long:"webhook" env:"WEBHOOK" description:"Send audit events to the webhook URL"`
+		Key      string `long:"key" env:"KEY" description:"Secret key to fingerprint access codes in audit events. If not set - random key is used, which is not shared between instances and restarts"`
+	} `group:"Audit log configuration" namespace:"audit" env-namespace:"AUDIT"`
+	Limits struct {
+		Store string `long:"store" env:"STORE" description:"Where to keep submission counters for form limits. Auto means database for database storage, otherwise memory" default:"auto" choice:"auto" choice:"memory" choice:"database"`
+	} `group:"Submission limits configuration" namespace:"limits" env-namespace:"LIMITS"`
+	Codes struct {
+		Token string `long:"token" env:"TOKEN" description:"Bearer token for issued access codes API. If not set - API is disabled"`
+	} `group:"Issued access codes configuration" namespace:"codes" env-namespace:"CODES"`
+	API struct {
+		Keys string `long:"keys" env:"KEYS" description:"YAML file with global API keys for machine clients"`
+	} `group:"API keys configuration" namespace:"api" env-namespace:"API"`
+	Links struct {
+		Key   string        `long:"key" env:"KEY" description:"Secret key (at least 16 bytes) to sign prefill links. If not set - signed links are disabled"`
+		TTL   time.Duration `long:"ttl" env:"TTL" description:"Default lifetime of signed links" default:"168h"`
+		Token string        `long:"token" env:"TOKEN" description:"Bearer token for signing links API. If not set - API is disabled"`
+	} `group:"Signed links configuration" namespace:"links" env-namespace:"LINKS"`
+	RateLimit struct {
+		Backend  string         `long:"backend" env:"BACKEND" description:"Where to keep rate limit counters" default:"memory" choice:"memory" choice:"redis"`
+		RedisURL string         `long:"redis-url" env:"REDIS_URL" description:"Redis URL for redis backend"`
+		Global   ratelimit.Rate `long:"global" env:"GLOBAL" description:"Maximum number of requests per client to forms and listing, for example 60/1m. Disabled if not set"`
+		Codes    ratelimit.Rate `long:"codes" env:"CODES" description:"Maximum number of failed access code attempts per client and form. Disabled if empty" default:"10/15m"`
+		APIKeys  ratelimit.Rate `long:"api-keys" env:"API_KEYS" description:"Maximum number of failed API key attempts per client IP. Disabled if empty" default:"10/15m"`
+		ByUser   bool           `long:"by-user" env:"BY_USER" description:"Identify authorized clients by user name instead of IP"`
+	} `group:"Rate limiting configuration" namespace:"ratelimit" env-namespace:"RATELIMIT"`
+	Health struct {
+		Timeout    time.Duration `long:"timeout" env:"TIMEOUT" description:"Timeout for all readiness checks" default:"5s"`
+		Saturation float64       `long:"saturation" env:"SATURATION" description:"Notification queue fill ratio (0..1) after which service is not ready" default:"0.9"`
+	} `group:"Health checks configuration" namespace:"health" env-namespace:"HEALTH"`
 	HTTP struct {
-		Assets       string        `long:"assets" env:"ASSETS" description:"Directory for assets (static) files"`
-		Bind         string        `long:"bind" env:"BIND" description:"Binding address" default:":8080"`
-		DisableXSRF  bool          `long:"disable-xsrf" env:"DISABLE_XSRF" description:"Disable XSRF validation. Useful for API"`
-		TLS          bool          `long:"tls" env:"TLS" description:"Enable TLS"`
-		Key          string        `long:"key" env:"KEY" description:"Private TLS key" default:"server.key"`
-		Cert         string        `long:"cert" env:"CERT" description:"Public TLS certificate" default:"server.crt"`
-		ReadTimeout  time.Duration `long:"read-timeout" env:"READ_TIMEOUT" description:"Read timeout to prevent slow client attack" default:"5s"`
-		WriteTimeout time.Duration `long:"write-timeout" env:"WRITE_TIMEOUT" description:"Write timeout to prevent slow consuming clients attack" default:"5s"`
+		Assets         string        `long:"assets" env:"ASSETS" description:"Directory for assets (static) files"`
+		Views          string        `long:"views" env:"VIEWS" description:"Directory with templates (.gohtml) which replace built-in ones with the same name"`
+		TrustedProxies []string      `long:"trusted-proxies" env:"TRUSTED_PROXIES" env-delim:"," description:"Reverse proxy IPs or CIDRs allowed to set X-Forwarded-For" default:"127.0.0.1/32" default:"::1/128"`
+		Bind           string        `long:"bind" env:"BIND" description:"Binding address" default:":8080"`
+		DisableXSRF    bool          `long:"disable-xsrf" env:"DISABLE_XSRF" description:"Disable XSRF validation. Useful for API"`
+		MetricsBind    string        `long:"metrics-bind" env:"METRICS_BIND" description:"Separate binding address for Prometheus metrics on /metrics, ex: 127.0.0.1:9090. Disabled if not set"`
+		TLS            bool          `long:"tls" env:"TLS" description:"Enable TLS"`
+		Key            string        `long:"key" env:"KEY" description:"Private TLS key" default:"server.key"`
+		Cert           string        `long:"cert" env:"CERT" description:"Public TLS certificate" default:"server.crt"`
+		ReadTimeout    time.Duration `long:"read-timeout" env:"READ_TIMEOUT" description:"Read timeout to prevent slow client attack" default:"5s"`
+		WriteTimeout   time.Duration `long:"write-timeout" env:"WRITE_TIMEOUT" description:"Write timeout to prevent slow consuming clients attack" default:"5s"`
 	} `group:"HTTP server configuration" namespace:"http" env-namespace:"HTTP"`
 	OIDC struct {
-		Enable              bool   `long:"enable" env:"ENABLE" description:"Enable OIDC protection"`
-		ClientID            string `long:"client-id" env:"CLIENT_ID" description:"OIDC client ID"`
-		ClientSecret        string `long:"client-secret" env:"CLIENT_SECRET" description:"OIDC client secret"`
-		Issuer              string `long:"issuer" env:"ISSUER" description:"Issuer URL (without .well-known)"`
-		RedisURL            string `long:"redis-url" env:"REDIS_URL" description:"Optional Redis URL for sessions. If not set - in-memory will be used"`
-		RedisIdle           int    `long:"redis-idle" env:"REDIS_IDLE" description:"Redis maximum number of idle connections" default:"1"`
-		RedisMaxConnections int    `long:"redis-max-connections" env:"REDIS_MAX_CONNECTIONS" description:"Redis maximum number of active connections" default:"10"`
+		Enable              bool     `long:"enable" env:"ENABLE" description:"Enable OIDC protection"`
+		ClientID            string   `long:"client-id" env:"CLIENT_ID" description:"OIDC client ID"`
+		ClientSecret        string   `long:"client-secret" env:"CLIENT_SECRET" description:"OIDC client secret"`
+		Issuer              string   `long:"issuer" env:"ISSUER" description:"Issuer URL (without .well-known)"`
+		Name                string   `long:"name" env:"NAME" description:"Provider name, available in policies as provider" default:"default"`
+		Title               string   `long:"title" env:"TITLE" description:"Provider title in provider chooser" default:"Single Sign-On"`
+		Providers           string   `long:"providers" env:"PROVIDERS" description:"YAML file with additional OIDC providers"`
+		RedisURL            string   `long:"redis-url" env:"REDIS_URL" description:"Optional Redis URL for sessions (OIDC and htpasswd). If not set - in-memory will be used"`
+		RedisIdle           int      `long:"redis-idle" env:"REDIS_IDLE" description:"Redis maximum number of idle connections" default:"1"`
+		RedisMaxConnections int      `long:"redis-max-connections" env:"REDIS_MAX_CONNECTIONS" description:"Redis maximum number of active connections" default:"10"`
+		Scopes              []string `long:"scopes" env:"SCOPES" env-delim:"," description:"Requested scopes, openid is always added" default:"openid" default:"profile"`
+		UserClaims          []string `long:"user-claims" env:"USER_CLAIMS" env-delim:"," description:"Claims for user name, first non-empty is used" default:"preferred_username" default:"email" default:"sub"`
+		EmailClaim          string   `long:"email-claim" env:"EMAIL_CLAIM" description:"Claim for email" default:"email"`
+		GroupsClaims        []string `long:"groups-claims" env:"GROUPS_CLAIMS" env-delim:"," description:"Claims for groups, all values are merged. Nested claims are separated by dot" default:"groups"`
 	} `group:"OIDC configuration" namespace:"oidc" env-namespace:"OIDC"`
+	Htpasswd struct {
+		File   string         `long:"file" env:"FILE" description:"Htpasswd file with bcrypt entries. Enables built-in login page"`
+		Groups string         `long:"groups" env:"GROUPS" description:"Optional group file in Apache format (group: user1 user2)"`
+		Rate   ratelimit.Rate `long:"rate" env:"RATE" description:"Maximum number of failed login attempts per client. Disabled if empty" default:"10/15m"`
+	} `group:"Htpasswd authentication" namespace:"htpasswd" env-namespace:"HTPASSWD"`
 	Captcha struct {
 		Turnstile captcha.Turnstile `group:"Cloudflare Turnstile" namespace:"turnstile" env-namespace:"TURNSTILE"`
+		HCaptcha  captcha.HCaptcha  `group:"hCaptcha" namespace:"hcaptcha" env-namespace:"HCAPTCHA"`
+		ReCaptcha captcha.ReCaptcha `group:"Google reCAPTCHA" namespace:"recaptcha" env-namespace:"RECAPTCHA"`
+		PoW       captcha.PoWConfig `group:"Proof-of-work captcha" namespace:"pow" env-namespace:"POW"`
 	} `group:"Captcha configurations" namespace:"captcha" env-namespace:"CAPTCHA"`
-	ServerURL string `long:"server-url" env:"SERVER_URL" description:"Server public URL. Used for OIDC redirects. If not set - it will try to deduct"`
+	Spam struct {
+		Honeypot string        `long:"honeypot" env:"HONEYPOT" description:"Name of hidden honeypot field which should stay empty. Disabled if not set"`
+		MinTime  time.Duration `long:"min-time" env:"MIN_TIME" description:"Minimal time between form rendering and submission. Disabled if zero"`
+		MaxTime  time.Duration `long:"max-time" env:"MAX_TIME" description:"Maximum time between form rendering and submission. Disabled if zero"`
+		Key      string        `long:"key" env:"KEY" description:"Secret key (at least 16 bytes) to sign render time. If not set - random key is used, which is not shared between instances"`
+	} `group:"Spam protection" namespace:"spam" env-namespace:"SPAM"`
+	I18n struct {
+		Dir     string `long:"dir" env:"DIR" description:"Directory with additional message catalogs (<language>.yaml), extends and overrides built-in ones"`
+		Default string `long:"default" env:"DEFAULT" description:"Language used when client's language is not supported" default:"en"`
+	} `group:"Localization" namespace:"i18n" env-namespace:"I18N"`
+	HTTPClient      httpclient.Config  `group:"Outgoing HTTP requests" namespace:"http-client" env-namespace:"HTTP_CLIENT"`
+	Tracing         tracing.Config     `group:"OpenTelemetry tracing" namespace:"tracing" env-namespace:"TRACING"`
+	ForwardAuth     forwardauth.Config `group:"Forward authentication (trusted headers)" namespace:"forward-auth" env-namespace:"FORWARD_AUTH"`
+	ServerURL       string             `long:"server-url" env:"SERVER_URL" description:"Server public URL. Used for OIDC redirects. If not set - it will try to deduct"`
+	ShutdownTimeout time.Duration      `long:"shutdown-timeout" env:"SHUTDOWN_TIMEOUT" description:"Grace period to finish in-flight requests and pending notifications on shutdown" default:"30s"`
 }
 
 func main() {
@@ -98,12 +192,18 @@ func main() {
 	parser := flags.NewParser(&config, flags.Default)
 	parser.ShortDescription = name
 	parser.LongDescription = fmt.Sprintf("%s \n%s %s, commit %s, built at %s by %s\nAuthor: reddec <owner@reddec.net>", description, name, version, commit, date, builtBy)
+	parser.SubcommandsOptional = true
+	addCommands(parser, &config)
 	_, err := parser.Parse()
 	if err != nil {
 		os.Exit(1)
 	}
+	if parser.Active != nil {
+		// sub-command already executed
+		return
+	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	if err := run(ctx, config); err != nil {
@@ -114,46 +214,98 @@ func main() {
 
 //nolint:cyclop
 func run(ctx context.Context, config Config) error {
+	shutdownTracing, err := tracing.Setup(ctx, config.Tracing)
+	if err != nil {
+		return fmt.Errorf("setup tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Warn("failed flush traces", "error", err)
+		}
+	}()
+	if config.Tracing.Enabled() {
+		slog.Info("tracing enabled", "endpoint", config.Tracing.Endpoint)
+	}
+
 	router := chi.NewRouter()
 
+	// client IP (for rate limits, audit, policies) from X-Forwarded-For only behind trusted proxies
+	proxies, err := web.NewProxies(config.HTTP.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("trusted proxies: %w", err)
+	}
+	router.Use(proxies.Handler)
+
+	readiness := health.New(config.Health.Timeout)
+
 	// mock auth by default
 	var authMiddleware = func(next http.Handler) http.Handler {
 		return next
 	}
 
+	if countTrue(config.OIDC.Enable, config.ForwardAuth.Enable, config.Htpasswd.File != "") > 1 {
+		return fmt.Errorf("only one of OIDC, forward authentication or htpasswd can be enabled")
+	}
+
+	// rate limiting for forms, listing and login
+	rateLimiter, err := config.createRateLimiter(ctx, readiness)
+	if err != nil {
+		return fmt.Errorf("create rate limiter: %w", err)
+	}
+
+	// built-in templates, optionally replaced by user-defined
+	views, err := assets.ViewsFrom(config.HTTP.Views)
+	if err != nil {
+		return fmt.Errorf("load views: %w", err)
+	}
+	if config.HTTP.Views != "" {
+		slog.Info("user-defined views enabled", "views-dir", config.HTTP.Views)
+	}
+
+	locales, err := config.locales()
+	if err != nil {
+		return fmt.Errorf("load message catalogs: %w", err)
+	}
+
 	if config.OIDC.Enable {
-		// setup auth provider from OIDC
-		slog.Info("oidc enabled", "issuer", config.OIDC.Issuer)
-		sessionManager := scs.New() // by default in-memory session store
-		router.Use(sessionManager.LoadAndSave)
-
-		if config.OIDC.RedisURL != "" {
-			// setup redis pool for sessions
-			slog.Info("oidc redis session storage enabled")
-			redisPool := &redis.Pool{
-				Dial: func() (redis.Conn, error) {
-					return redis.DialURLContext(ctx, config.OIDC.RedisURL)
-				},
-				MaxIdle:     config.OIDC.RedisIdle,
-				MaxActive:   config.OIDC.RedisMaxConnections,
-				IdleTimeout: time.Hour,
-			}
-			defer redisPool.Close()
-			sessionManager.Store = redisstore.New(redisPool)
-		} else {
-			slog.Info("oidc session storage in-memory")
-		}
+		// setup auth providers from OIDC
+		sessionManager, closeSessions := config.createSessions(ctx, router, readiness)
+		defer closeSessions()
 
-		auth, err := config.createAuth(ctx, sessionManager)
+		auth, err := config.createAuth(ctx, sessionManager, views, locales)
 		if err != nil {
 			return fmt.Errorf("create auth: %w", err)
 		}
 		authMiddleware = auth.Secure
-		router.Mount(oidclogin.Prefix, auth)
+		router.Mount(oidcauth.Prefix, auth)
+	} else if config.ForwardAuth.Enable {
+		// credentials from headers set by authentication proxy
+		slog.Info("forward authentication enabled", "user", config.ForwardAuth.User, "trusted", config.ForwardAuth.Trusted)
+		auth, err := forwardauth.New(config.ForwardAuth)
+		if err != nil {
+			return fmt.Errorf("create forward auth: %w", err)
+		}
+		authMiddleware = auth.Secure
+	} else if config.Htpasswd.File != "" {
+		// built-in login page backed by htpasswd file
+		slog.Info("htpasswd authentication enabled", "file", config.Htpasswd.File, "groups", config.Htpasswd.Groups)
+		sessionManager, closeSessions := config.createSessions(ctx, router, readiness)
+		defer closeSessions()
+
+		auth, err := config.createHtpasswd(sessionManager, rateLimiter, views, locales)
+		if err != nil {
+			return fmt.Errorf("create htpasswd auth: %w", err)
+		}
+		authMiddleware = auth.Secure
+		router.Mount(htpasswd.Prefix, auth)
 	} else {
 		slog.Info("no authorization used")
 	}
 
+	// probes are registered after auth setup: sessions middleware must be added before any route
+	router.Get("/healthz", health.Liveness)
+	router.Get("/readyz", readiness.Readiness)
+
 	// static dir and user-defined asset dir are unprotected
 	router.Mount("/static/", http.FileServer(http.FS(assets.Static)))
 	if config.HTTP.Assets != "" {
@@ -167,6 +319,16 @@ func run(ctx context.Context, config Config) error {
 		return fmt.Errorf("read configs in %q: %w", config.Configs, err)
 	}
 
+	// global API keys for machine clients
+	var apiKeys schema.APIKeys
+	if config.API.Keys != "" {
+		apiKeys, err = schema.APIKeysFromFile(config.API.Keys)
+		if err != nil {
+			return fmt.Errorf("read API keys: %w", err)
+		}
+		slog.Info("global API keys loaded", "keys", len(apiKeys))
+	}
+
 	// create results storage
 	store, err := config.createStorage(ctx)
 	if err != nil {
@@ -174,19 +336,136 @@ func run(ctx context.Context, config Config) error {
 	}
 	defer store.Close()
 	slog.Info("storage prepared")
+	if pinger, ok := store.(storage.Pinger); ok {
+		readiness.Add("storage", pinger.Ping)
+	}
+
+	// notifications delivery log
+	deliveryLog, err := config.createDeliveryLog(ctx, store)
+	if err != nil {
+		return fmt.Errorf("create delivery log: %w", err)
+	}
+	if config.Deliveries.Token != "" {
+		slog.Info("delivery log API enabled")
+		router.With(bearerAuth(config.Deliveries.Token)).Get("/api/deliveries/{submission}", deliveries.Handler(deliveryLog))
+	}
+
+	// client for outgoing requests: webhooks, hooks and captcha validation
+	httpClient, err := httpclient.New(config.HTTPClient)
+	if err != nil {
+		return fmt.Errorf("create HTTP client: %w", err)
+	}
 
 	// webhooks dispatcher
-	webhooks := webhook.New(config.Webhooks.Buffer)
+	webhooks := webhook.New(config.Webhooks.Buffer, webhook.WithLog(deliveryLog), webhook.WithClient(httpClient))
 	// amqp dispatcher - lazy loading, so URL validity not critical here
-	broker := amqp.New(config.AMQP.URL, config.AMQP.Buffer)
+	broker := amqp.New(config.AMQP.URL, config.AMQP.Buffer, amqp.WithLog(deliveryLog))
+
+	// audit log of access and submissions
+	auditLog, err := config.createAudit(ctx, store, webhooks)
+	if err != nil {
+		return fmt.Errorf("create audit log: %w", err)
+	}
+	defer auditLog.Close()
+
+	// counters for submission limits
+	limitsStore, err := config.createLimits(ctx, store)
+	if err != nil {
+		return fmt.Errorf("create limits store: %w", err)
+	}
+
+	// issued access codes
+	codesStore, err := config.createCodes(ctx, store, forms)
+	if err != nil {
+		return fmt.Errorf("create codes store: %w", err)
+	}
+	var codesBackend engine.CodesStore // keep interface nil if there is no store
+	if codesStore != nil {
+		codesBackend = codesStore
+	}
+	if codesStore != nil && config.Codes.Token != "" {
+		slog.Info("access codes API enabled")
+		router.With(bearerAuth(config.Codes.Token)).Mount("/api/codes/{form}", codes.Handler(codesStore, issuedCodesForms(forms)))
+	}
+
+	// signed prefill links
+	var linksVerifier engine.LinkVerifier // keep interface nil if links are disabled
+	if config.Links.Key != "" {
+		signer, err := signed.New([]byte(config.Links.Key))
+		if err != nil {
+			return fmt.Errorf("create links signer: %w", err)
+		}
+		linksVerifier = signer
+		slog.Info("signed links enabled")
+		if config.Links.Token != "" {
+			slog.Info("signed links API enabled")
+			router.With(bearerAuth(config.Links.Token)).Post("/api/links/{form}", signed.Handler(signer, formNames(forms), config.ServerURL, config.Links.TTL))
+		}
+	}
+
+	// used proof-of-work challenges are kept with rate limit counters
+	captchas, err := config.captcha(httpClient, rateLimiter)
+	if err != nil {
+		return fmt.Errorf("create captcha: %w", err)
+	}
+
+	spamChecks, err := config.spamChecks()
+	if err != nil {
+		return fmt.Errorf("create spam checks: %w", err)
+	}
+
+	readiness.Add("webhooks-queue", health.Saturation(webhooks, config.Health.Saturation))
+	readiness.Add("amqp-queue", health.Saturation(broker, config.Health.Saturation))
+	if usesAMQP(forms) {
+		readiness.Add("amqp", broker.Ping)
+	}
+
+	// metrics are not exposed on public address
+	var metricsServer *http.Server
+	var metricsListener net.Listener
+	if config.HTTP.MetricsBind != "" {
+		// bind before start, so invalid or busy address fails immediately instead of on shutdown
+		metricsListener, err = net.Listen("tcp", config.HTTP.MetricsBind)
+		if err != nil {
+			return fmt.Errorf("bind metrics: %w", err)
+		}
+		defer metricsListener.Close()
+		metrics.RegisterQueue(deliveries.KindWebhook, webhooks)
+		metrics.RegisterQueue(deliveries.KindAMQP, broker)
+		metricsRouter := http.NewServeMux()
+		metricsRouter.Handle("/metrics", promhttp.Handler())
+		metricsServer = &http.Server{
+			Addr:         config.HTTP.MetricsBind,
+			Handler:      metricsRouter,
+			ReadTimeout:  config.HTTP.ReadTimeout,
+			WriteTimeout: config.HTTP.WriteTimeout,
+		}
+	}
 
 	srv, err := engine.New(engine.Config{
 		Forms:           forms,
 		Storage:         store,
 		WebhooksFactory: webhooks,
 		AMQPFactory:     broker,
-		Listing:         !config.DisableListing,
-		Captcha:         config.captcha(),
+		HooksFactory:    hooks.New(hooks.WithClient(httpClient)),
+		Audit:           auditLog,
+		AuditKey:        []byte(config.Audit.Key),
+		Limits:          limitsStore,
+		Codes:           codesBackend,
+		Links:           linksVerifier,
+		APIKeys:         apiKeys,
+		RateLimit: engine.RateLimit{
+			Limiter: rateLimiter,
+			Global:  config.RateLimit.Global,
+			Codes:   config.RateLimit.Codes,
+			APIKeys: config.RateLimit.APIKeys,
+			ByUser:  config.RateLimit.ByUser,
+		},
+		Listing:    !config.DisableListing,
+		Captcha:    captchas,
+		SpamChecks: spamChecks,
+		Locales:    locales,
+		Views:      views,
 	},
 		engine.WithXSRF(!config.HTTP.DisableXSRF),
 	)
@@ -194,43 +473,52 @@ func run(ctx context.Context, config Config) error {
 		return fmt.Errorf("create engine: %w", err)
 	}
 
+	// interactive authorization is skipped only for valid API keys, other bearer tokens (ex: from oauth2-proxy) are
+	// passed to it as usual; API key scopes are checked by engine
+	var allKeys = []schema.APIKeys{apiKeys}
+	for _, form := range forms {
+		allKeys = append(allKeys, form.APIKeys)
+	}
+	keys := engine.NewKeys(rateLimiter, config.RateLimit.APIKeys, allKeys...)
+
 	router.Group(func(r chi.Router) {
 		r.Use(owasp)
-		r.Use(authMiddleware)
-		r.Use(func(handler http.Handler) http.Handler {
-			return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-				creds := credentialsFromRequest(request)
-				reqCtx := schema.WithCredentials(request.Context(), creds)
-				handler.ServeHTTP(writer, request.WithContext(reqCtx))
-			})
-		})
+		r.Use(keys.Skip(authMiddleware))
 		r.Mount("/", srv)
 	})
 
+	// in-flight requests and notifications are not interrupted by signal, only after grace period
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
 	server := &http.Server{
 		Addr:         config.HTTP.Bind,
-		Handler:      router,
+		Handler:      otelhttp.NewHandler(router, "http.server"),
 		ReadTimeout:  config.HTTP.ReadTimeout,
 		WriteTimeout: config.HTTP.WriteTimeout,
 		BaseContext: func(listener net.Listener) context.Context {
-			return ctx
+			return workCtx
 		},
 	}
 
-	var wg multierror.Group
+	var workers sync.WaitGroup
 
-	wg.Go(func() error {
-		webhooks.Run(ctx)
-		return nil
-	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		webhooks.Run(workCtx)
+	}()
 
 	for i := 0; i < config.AMQP.Workers; i++ {
-		wg.Go(func() error {
-			broker.Run(ctx)
-			return nil
-		})
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			broker.Run(workCtx)
+		}()
 	}
 
+	var wg multierror.Group
+
 	wg.Go(func() error {
 		var err error
 		if config.HTTP.TLS {
@@ -244,14 +532,56 @@ func run(ctx context.Context, config Config) error {
 		return err
 	})
 
+	if metricsServer != nil {
+		wg.Go(func() error {
+			err := metricsServer.Serve(metricsListener)
+			if errors.Is(err, http.ErrServerClosed) {
+				err = nil
+			}
+			return err
+		})
+	}
+
 	wg.Go(func() error {
 		<-ctx.Done()
-		return server.Close()
+		slog.Info("shutting down", "grace-period", config.ShutdownTimeout)
+		time.AfterFunc(config.ShutdownTimeout, cancelWork)
+
+		// stop accepting new connections and wait for in-flight submissions
+		if err := server.Shutdown(workCtx); err != nil {
+			slog.Warn("in-flight requests interrupted", "error", err)
+			_ = server.Close()
+		}
+		if metricsServer != nil {
+			_ = metricsServer.Close()
+		}
+
+		// no more submissions - stop accepting notifications and let workers drain queues
+		webhooks.Close()
+		broker.Close()
+		return nil
 	})
 
 	slog.Info("ready", "bind", config.HTTP.Bind, "storage", config.Storage)
 
-	return wg.Wait().ErrorOrNil()
+	err = wg.Wait().ErrorOrNil()
+	workers.Wait()
+	reportDropped(webhooks, broker)
+	return err
+}
+
+// reportDropped records unprocessed notifications after shutdown.
+func reportDropped(webhooks *webhook.Dispatcher, broker *amqp.AMQP) {
+	ctx, cancel := context.WithTimeout(context.Background(), dropReportTimeout)
+	defer cancel()
+
+	droppedWebhooks := webhooks.Discard(ctx)
+	droppedMessages := broker.Discard(ctx)
+	if droppedWebhooks+droppedMessages > 0 {
+		slog.Warn("notifications dropped during shutdown", "webhooks", droppedWebhooks, "amqp", droppedMessages)
+		return
+	}
+	slog.Info("all notifications processed")
 }
 
 func (cfg *Config) createStorage(ctx context.Context) (storage.ClosableStorage, error) {
@@ -276,27 +606,176 @@ func (cfg *Config) createStorage(ctx context.Context) (storage.ClosableStorage,
 	}
 }
 
-func (cfg *Config) createAuth(ctx context.Context, sessionManager *scs.SessionManager) (service *oidclogin.OIDC, err error) {
-	return oidclogin.New(ctx, oidclogin.Config{
-		IssuerURL:      cfg.OIDC.Issuer,
-		ClientID:       cfg.OIDC.ClientID,
-		ClientSecret:   cfg.OIDC.ClientSecret,
-		ServerURL:      cfg.ServerURL,
-		Scopes:         []string{oidc.ScopeOpenID, "profile"},
-		SessionManager: sessionManager,
-		BeforeAuth: func(writer http.ResponseWriter, req *http.Request) error {
-			sessionManager.Put(req.Context(), "redirect-to", req.URL.String())
-			return nil
-		},
-		PostAuth: func(writer http.ResponseWriter, req *http.Request, idToken *oidc.IDToken) error {
-			to := sessionManager.PopString(req.Context(), "redirect-to")
-			if to != "" {
-				writer.Header().Set("Location", to)
-			}
-			return nil
-		},
-		Logger: &LoggerFunc{},
-	})
+func (cfg *Config) createDeliveryLog(ctx context.Context, store storage.ClosableStorage) (deliveries.Log, error) {
+	switch cfg.Deliveries.Log {
+	case "none":
+		return deliveries.Nop{}, nil
+	case "memory", "":
+		return deliveries.NewMemory(cfg.Deliveries.Size), nil
+	case "database":
+		db, ok := store.(storage.DBStore)
+		if !ok {
+			return nil, fmt.Errorf("database delivery log requires database storage")
+		}
+		return deliveries.NewDB(ctx, db.DB())
+	default:
+		return nil, fmt.Errorf("unknown delivery log type %q", cfg.Deliveries.Log)
+	}
+}
+
+func (cfg *Config) createAudit(ctx context.Context, store storage.ClosableStorage, webhooks *webhook.Dispatcher) (audit.Multi, error) {
+	var sinks audit.Multi
+	if cfg.Audit.File != "" {
+		slog.Info("audit log to file enabled", "file", cfg.Audit.File)
+		file, err := audit.NewFile(cfg.Audit.File)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, file)
+	}
+	if cfg.Audit.Database {
+		db, ok := store.(storage.DBStore)
+		if !ok {
+			_ = sinks.Close()
+			return nil, fmt.Errorf("database audit log requires database storage")
+		}
+		sink, err := audit.NewDB(ctx, db.DB())
+		if err != nil {
+			_ = sinks.Close()
+			return nil, err
+		}
+		slog.Info("audit log to database enabled")
+		sinks = append(sinks, sink)
+	}
+	if cfg.Audit.Webhook != "" {
+		slog.Info("audit log to webhook enabled")
+		// audit events are not submissions: delivery log would keep them under empty submission forever
+		sinks = append(sinks, audit.NewNotification(webhooks.CreateUnlogged(schema.Webhook{URL: cfg.Audit.Webhook})))
+	}
+	return sinks, nil
+}
+
+// createCodes returns store for issued access codes if database storage is used, otherwise nil.
+func (cfg *Config) createCodes(ctx context.Context, store storage.ClosableStorage, forms []schema.Form) (*codes.DB, error) {
+	db, ok := store.(storage.DBStore)
+	if !ok {
+		if len(issuedCodesForms(forms)) > 0 {
+			return nil, fmt.Errorf("issued access codes require database storage")
+		}
+		return nil, nil //nolint:nilnil
+	}
+	return codes.NewDB(ctx, db.DB())
+}
+
+func (cfg *Config) createLimits(ctx context.Context, store storage.ClosableStorage) (limits.Store, error) {
+	db, isDB := store.(storage.DBStore)
+	switch cfg.Limits.Store {
+	case "memory":
+		return limits.NewMemory(), nil
+	case "auto", "":
+		if !isDB {
+			return limits.NewMemory(), nil
+		}
+		return limits.NewDB(ctx, db.DB())
+	case "database":
+		if !isDB {
+			return nil, fmt.Errorf("database limits store requires database storage")
+		}
+		return limits.NewDB(ctx, db.DB())
+	default:
+		return nil, fmt.Errorf("unknown limits store type %q", cfg.Limits.Store)
+	}
+}
+
+func (cfg *Config) createRateLimiter(ctx context.Context, readiness *health.Checker) (ratelimit.Limiter, error) {
+	switch cfg.RateLimit.Backend {
+	case "memory", "":
+		return ratelimit.NewMemory(), nil
+	case "redis":
+		if cfg.RateLimit.RedisURL == "" {
+			return nil, fmt.Errorf("redis rate limit backend requires redis URL")
+		}
+		slog.Info("rate limit counters in redis")
+		pool := newRedisPool(ctx, cfg.RateLimit.RedisURL, defaultRedisIdle, defaultRedisConnections)
+		readiness.Add("ratelimit-redis", pingRedis(pool))
+		return ratelimit.NewRedis(pool), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", cfg.RateLimit.Backend)
+	}
+}
+
+// createSessions creates session manager and attaches it to the router. Sessions are kept in Redis if configured,
+// otherwise in-memory. Returned function releases resources.
+func (cfg *Config) createSessions(ctx context.Context, router chi.Router, readiness *health.Checker) (*scs.SessionManager, func()) {
+	sessionManager := scs.New() // by default in-memory session store
+	router.Use(sessionManager.LoadAndSave)
+
+	if cfg.OIDC.RedisURL != "" {
+		// setup redis pool for sessions
+		slog.Info("redis session storage enabled")
+		redisPool := newRedisPool(ctx, cfg.OIDC.RedisURL, cfg.OIDC.RedisIdle, cfg.OIDC.RedisMaxConnections)
+		sessionManager.Store = redisstore.New(redisPool)
+		readiness.Add("redis", pingRedis(redisPool))
+		return sessionManager, func() { _ = redisPool.Close() }
+	}
+	slog.Info("session storage in-memory")
+	return sessionManager, func() {}
+}
+
+func (cfg *Config) createHtpasswd(sessionManager *scs.SessionManager, limiter ratelimit.Limiter, views fs.FS, locales *i18n.Bundle) (*htpasswd.Auth, error) {
+	view, err := assets.ParseView(views, "login.gohtml")
+	if err != nil {
+		return nil, fmt.Errorf("login view: %w", err)
+	}
+	if err := assets.RequireFields(view, "EmbedXSRF"); err != nil {
+		return nil, fmt.Errorf("login view: %w", err)
+	}
+	users, err := htpasswd.LoadUsers(cfg.Htpasswd.File)
+	if err != nil {
+		return nil, fmt.Errorf("load users: %w", err)
+	}
+	var groups htpasswd.Groups
+	if cfg.Htpasswd.Groups != "" {
+		groups, err = htpasswd.LoadGroups(cfg.Htpasswd.Groups)
+		if err != nil {
+			return nil, fmt.Errorf("load groups: %w", err)
+		}
+	}
+	slog.Info("htpasswd users loaded", "users", len(users))
+	return htpasswd.New(users, groups, sessionManager, htpasswd.WithRateLimit(limiter, cfg.Htpasswd.Rate), htpasswd.WithView(view), htpasswd.WithLocales(locales)), nil
+}
+
+func (cfg *Config) createAuth(ctx context.Context, sessionManager *scs.SessionManager, views fs.FS, locales *i18n.Bundle) (*oidcauth.Auth, error) {
+	view, err := assets.ParseView(views, "providers.gohtml")
+	if err != nil {
+		return nil, fmt.Errorf("providers view: %w", err)
+	}
+	var providers []oidcauth.Provider
+	if cfg.OIDC.Issuer != "" {
+		providers = append(providers, oidcauth.Provider{
+			Name:           cfg.OIDC.Name,
+			Title:          cfg.OIDC.Title,
+			Issuer:         cfg.OIDC.Issuer,
+			ClientID:       cfg.OIDC.ClientID,
+			ClientSecret:   cfg.OIDC.ClientSecret,
+			Scopes:         cfg.OIDC.Scopes,
+			UserClaims:     cfg.OIDC.UserClaims,
+			EmailClaim:     cfg.OIDC.EmailClaim,
+			GroupsClaims:   cfg.OIDC.GroupsClaims,
+			CallbackPrefix: oidcauth.Prefix, // keep callback URL of single-provider setups
+		})
+	}
+	if cfg.OIDC.Providers != "" {
+		list, err := oidcauth.ProvidersFromFile(cfg.OIDC.Providers)
+		if err != nil {
+			return nil, fmt.Errorf("load providers: %w", err)
+		}
+		providers = append(providers, list...)
+	}
+	for _, p := range providers {
+		slog.Info("oidc provider enabled", "name", p.Name, "issuer", p.Issuer)
+	}
+	return oidcauth.New(ctx, sessionManager, cfg.ServerURL, providers, oidcauth.WithView(view), oidcauth.WithLocales(locales))
 }
 
 func (cfg *Config) shouldMigrate() bool {
@@ -317,28 +796,75 @@ func (cfg *Config) shouldMigrate() bool {
 	return migrate && err == nil
 }
 
-func (cfg *Config) captcha() []web.Captcha {
-	var ans []web.Captcha
+func (cfg *Config) captcha(client *http.Client, seen ratelimit.Limiter) (map[string]web.Captcha, error) {
+	var ans = make(map[string]web.Captcha)
 	if cfg.Captcha.Turnstile.SiteKey != "" {
 		slog.Info("Cloudflare Turnstile captcha enabled")
-		ans = append(ans, &cfg.Captcha.Turnstile)
+		cfg.Captcha.Turnstile.Client = client
+		ans[captcha.NameTurnstile] = &cfg.Captcha.Turnstile
 	}
-	return ans
+	if cfg.Captcha.HCaptcha.SiteKey != "" {
+		slog.Info("hCaptcha enabled")
+		cfg.Captcha.HCaptcha.Client = client
+		ans[captcha.NameHCaptcha] = &cfg.Captcha.HCaptcha
+	}
+	if cfg.Captcha.ReCaptcha.SiteKey != "" {
+		slog.Info("Google reCAPTCHA enabled", "version", cfg.Captcha.ReCaptcha.Version)
+		cfg.Captcha.ReCaptcha.Client = client
+		ans[captcha.NameReCaptcha] = &cfg.Captcha.ReCaptcha
+	}
+	if cfg.Captcha.PoW.Enable {
+		slog.Info("proof-of-work captcha enabled", "difficulty", cfg.Captcha.PoW.Difficulty)
+		if cfg.Captcha.PoW.Key == "" {
+			slog.Warn("proof-of-work captcha key not set - challenges are valid only for this instance")
+		}
+		pow, err := captcha.NewPoW(cfg.Captcha.PoW, seen)
+		if err != nil {
+			return nil, fmt.Errorf("create proof-of-work captcha: %w", err)
+		}
+		ans[captcha.NamePoW] = pow
+	}
+	return ans, nil
 }
 
-type LoggerFunc struct{}
+func (cfg *Config) spamChecks() ([]web.SpamCheck, error) {
+	var ans []web.SpamCheck
+	if cfg.Spam.Honeypot != "" {
+		slog.Info("honeypot spam check enabled", "field", cfg.Spam.Honeypot)
+		ans = append(ans, &antispam.Honeypot{Field: cfg.Spam.Honeypot})
+	}
+	if cfg.Spam.MinTime > 0 || cfg.Spam.MaxTime > 0 {
+		slog.Info("timing spam check enabled", "min", cfg.Spam.MinTime, "max", cfg.Spam.MaxTime)
+		timing, err := antispam.NewTiming([]byte(cfg.Spam.Key), cfg.Spam.MinTime, cfg.Spam.MaxTime)
+		if err != nil {
+			return nil, err
+		}
+		ans = append(ans, timing)
+	}
+	return ans, nil
+}
 
-func (lf LoggerFunc) Log(level oidclogin.Level, message string) {
-	switch level {
-	case oidclogin.LogInfo:
-		slog.Info(message, "source", "oidc")
-	case oidclogin.LogWarn:
-		slog.Warn(message, "source", "oidc")
-	case oidclogin.LogError:
-		slog.Error(message, "source", "oidc")
-	default:
-		slog.Debug(message, "source", "oidc")
+// locales are built-in message catalogs extended by catalogs from directory (if set).
+func (cfg *Config) locales() (*i18n.Bundle, error) {
+	catalogs, err := i18n.Load(assets.InsideLocales())
+	if err != nil {
+		return nil, fmt.Errorf("built-in: %w", err)
+	}
+	if cfg.I18n.Dir != "" {
+		custom, err := i18n.Load(os.DirFS(cfg.I18n.Dir))
+		if err != nil {
+			return nil, fmt.Errorf("directory %q: %w", cfg.I18n.Dir, err)
+		}
+		for lang, messages := range custom {
+			catalogs[lang] = i18n.Merge(catalogs[lang], messages)
+		}
 	}
+	bundle, err := i18n.New(cfg.I18n.Default, catalogs)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("UI languages", "languages", bundle.Languages())
+	return bundle, nil
 }
 
 func owasp(next http.Handler) http.Handler {
@@ -351,33 +877,75 @@ func owasp(next http.Handler) http.Handler {
 	})
 }
 
-func credentialsFromRequest(req *http.Request) *schema.Credentials {
-	token := oidclogin.Token(req)
-	if token == nil {
-		return nil
+func newRedisPool(ctx context.Context, url string, idle, active int) *redis.Pool {
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURLContext(ctx, url)
+		},
+		MaxIdle:     idle,
+		MaxActive:   active,
+		IdleTimeout: time.Hour,
 	}
-	// optimized version to avoid multiple unmarshalling
-	var claims struct {
-		Username string   `json:"preferred_username"` //nolint:tagliatelle
-		Email    string   `json:"email"`
-		Groups   []string `json:"groups"`
+}
+
+func pingRedis(pool *redis.Pool) health.Check {
+	return func(ctx context.Context) error {
+		conn, err := pool.GetContext(ctx)
+		if err != nil {
+			return fmt.Errorf("get connection: %w", err)
+		}
+		defer conn.Close()
+		_, err = redis.DoContext(conn, ctx, "PING")
+		return err
+	}
+}
+
+func usesAMQP(forms []schema.Form) bool {
+	for _, f := range forms {
+		if len(f.AMQP) > 0 {
+			return true
+		}
 	}
-	_ = token.Claims(&claims)
-	// workaround for username
-	claims.Username = firstOf(claims.Username, claims.Email, token.Subject)
-	return &schema.Credentials{
-		User:   claims.Username,
-		Groups: claims.Groups,
-		Email:  claims.Email,
+	return false
+}
+
+func formNames(forms []schema.Form) utils.Set[string] {
+	var ans = utils.NewSet[string]()
+	for _, f := range forms {
+		ans.Add(f.Name)
+	}
+	return ans
+}
+
+func issuedCodesForms(forms []schema.Form) utils.Set[string] {
+	var ans = utils.NewSet[string]()
+	for _, f := range forms {
+		if f.IssuedCodes {
+			ans.Add(f.Name)
+		}
+	}
+	return ans
+}
+
+func bearerAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			value, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(value), []byte(token)) != 1 {
+				writer.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
 	}
 }
 
-func firstOf[T comparable](values ...T) T {
-	var def T
+func countTrue(values ...bool) int {
+	var n int
 	for _, v := range values {
-		if v != def {
-			return v
+		if v {
+			n++
 		}
 	}
-	return def
+	return n
 }
@@ -0,0 +1,123 @@
+// Package antispam provides invisible checks of form submissions: honeypot field and minimal/maximal time between
+// form rendering and submission.
+package antispam
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	NameHoneypot = "honeypot"
+	NameTiming   = "timing"
+
+	// FieldRendered is hidden field with signed time of form rendering.
+	FieldRendered = "_rendered"
+	minKeySize    = 16
+)
+
+var ErrShortKey = errors.New("timing key should be at least 16 bytes")
+
+// Honeypot is hidden (for humans) field which should stay empty. Naive bots fill all fields.
+type Honeypot struct {
+	Field string // field name, should not clash with form fields
+}
+
+func (h *Honeypot) Name() string {
+	return NameHoneypot
+}
+
+func (h *Honeypot) Embed() template.HTML {
+	field := template.HTMLEscapeString(h.Field)
+	//nolint:gosec
+	return template.HTML(`<div style="position:absolute;left:-10000px;top:auto;width:1px;height:1px;overflow:hidden" aria-hidden="true">` +
+		`<label for="` + field + `">Leave this field empty</label>` +
+		`<input type="text" id="` + field + `" name="` + field + `" value="" tabindex="-1" autocomplete="off"/>` +
+		`</div>`)
+}
+
+func (h *Honeypot) Fields() []string {
+	return []string{h.Field}
+}
+
+func (h *Honeypot) Check(form *http.Request) bool {
+	return form.FormValue(h.Field) == ""
+}
+
+// NewTiming creates check of time between form rendering and submission. Render time is signed by the key;
+// if key is empty, random key is used. Zero max means no upper limit.
+func NewTiming(key []byte, minTime, maxTime time.Duration) (*Timing, error) {
+	if len(key) == 0 {
+		key = make([]byte, sha256.Size)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("generate key: %w", err)
+		}
+	}
+	if len(key) < minKeySize {
+		return nil, ErrShortKey
+	}
+	return &Timing{key: key, min: minTime, max: maxTime, now: time.Now}, nil
+}
+
+type Timing struct {
+	key []byte
+	min time.Duration
+	max time.Duration
+	now func() time.Time
+}
+
+func (t *Timing) Name() string {
+	return NameTiming
+}
+
+func (t *Timing) Embed() template.HTML {
+	//nolint:gosec
+	return template.HTML(`<input type="hidden" name="` + FieldRendered + `" value="` + t.Stamp(t.now()) + `"/>`)
+}
+
+func (t *Timing) Fields() []string {
+	return []string{FieldRendered}
+}
+
+func (t *Timing) Check(form *http.Request) bool {
+	renderedAt, ok := t.Parse(form.FormValue(FieldRendered))
+	if !ok {
+		return false
+	}
+	elapsed := t.now().Sub(renderedAt)
+	return elapsed >= t.min && (t.max <= 0 || elapsed <= t.max)
+}
+
+// Stamp returns signed time: <unix millis>.<signature>.
+func (t *Timing) Stamp(at time.Time) string {
+	payload := strconv.FormatInt(at.UnixMilli(), 10)
+	return payload + "." + t.sign(payload)
+}
+
+// Parse signed time. Returns false if stamp is malformed or signature is invalid.
+func (t *Timing) Parse(stamp string) (time.Time, bool) {
+	payload, signature, ok := strings.Cut(stamp, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(t.sign(payload))) {
+		return time.Time{}, false
+	}
+	millis, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(millis), true
+}
+
+func (t *Timing) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
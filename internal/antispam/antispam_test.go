@@ -0,0 +1,58 @@
+package antispam_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/antispam"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(values url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestHoneypot(t *testing.T) {
+	check := &antispam.Honeypot{Field: "website"}
+	assert.Contains(t, string(check.Embed()), `name="website"`)
+	assert.True(t, check.Check(post(url.Values{"name": {"reddec"}})))
+	assert.True(t, check.Check(post(url.Values{"website": {""}})))
+	assert.False(t, check.Check(post(url.Values{"website": {"https://spam.example.com"}})))
+}
+
+func TestTiming(t *testing.T) {
+	_, err := antispam.NewTiming([]byte("short"), time.Second, 0)
+	require.ErrorIs(t, err, antispam.ErrShortKey)
+
+	check, err := antispam.NewTiming([]byte("0123456789abcdef"), 3*time.Second, time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, string(check.Embed()), `name="_rendered"`)
+
+	now := time.Now()
+	stamp := check.Stamp(now)
+	at, ok := check.Parse(stamp)
+	require.True(t, ok)
+	assert.Equal(t, now.UnixMilli(), at.UnixMilli())
+
+	submit := func(stamp string) bool {
+		return check.Check(post(url.Values{antispam.FieldRendered: {stamp}}))
+	}
+	assert.True(t, submit(check.Stamp(now.Add(-time.Minute))))
+	assert.False(t, submit(check.Stamp(now)), "too fast")
+	assert.False(t, submit(check.Stamp(now.Add(-2*time.Hour))), "too old")
+	assert.False(t, submit(""))
+	assert.False(t, submit(strings.Replace(check.Stamp(now.Add(-time.Minute)), "1", "2", 1)), "tampered")
+
+	other, err := antispam.NewTiming(nil, 0, 0)
+	require.NoError(t, err)
+	assert.False(t, submit(other.Stamp(now.Add(-time.Minute))), "other key")
+	assert.True(t, other.Check(post(url.Values{antispam.FieldRendered: {other.Stamp(now)}})), "no limits")
+}
@@ -15,6 +15,11 @@ var Static embed.FS
 //go:embed views
 var Views embed.FS
 
+// Locales stores built-in message catalogs for UI, one YAML file per language.
+//
+//go:embed locales
+var Locales embed.FS
+
 func InsideViews() fs.FS {
 	v, err := fs.Sub(Views, "views")
 	if err != nil {
@@ -22,3 +27,11 @@ func InsideViews() fs.FS {
 	}
 	return v
 }
+
+func InsideLocales() fs.FS {
+	v, err := fs.Sub(Locales, "locales")
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -0,0 +1,131 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"text/template/parse"
+
+	"github.com/reddec/web-form/internal/utils"
+)
+
+// ErrMissingField means that view doesn't use required field of render context.
+var ErrMissingField = errors.New("required field is not used")
+
+// ViewsFrom returns views where files from the directory replace embedded ones with the same name.
+// Empty directory means embedded views only.
+func ViewsFrom(dir string) (fs.FS, error) {
+	if dir == "" {
+		return InsideViews(), nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("views directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("views directory %q: %w", dir, fs.ErrInvalid)
+	}
+	return &overlayFS{primary: os.DirFS(dir), fallback: InsideViews()}, nil
+}
+
+// ParseView parses base template and overlays (in order) with common template functions.
+func ParseView(src fs.FS, base string, overlay ...string) (*template.Template, error) {
+	var root = template.New("").Funcs(utils.TemplateFuncs())
+	var files = append([]string{base}, overlay...)
+
+	for _, file := range files {
+		content, err := fs.ReadFile(src, file)
+		if err != nil {
+			return nil, fmt.Errorf("read %q: %w", file, err)
+		}
+		sub, err := root.Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("parse %q: %w", file, err)
+		}
+		root = sub
+	}
+	return root, nil
+}
+
+// RequireFields checks that view (any of its templates) uses fields of render context, for example embeds of hidden
+// inputs without which submissions are always rejected (ex: EmbedXSRF). Both .Field and $.Field are accepted.
+func RequireFields(view *template.Template, fields ...string) error {
+	var used = make(map[string]bool)
+	for _, t := range view.Templates() {
+		if t.Tree != nil {
+			collectFields(t.Tree.Root, used)
+		}
+	}
+	for _, field := range fields {
+		if !used[field] {
+			return fmt.Errorf("%w: %s", ErrMissingField, field)
+		}
+	}
+	return nil
+}
+
+// collectFields adds names of root context fields used in template node.
+//
+//nolint:cyclop
+func collectFields(node parse.Node, used map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, item := range n.Nodes {
+			collectFields(item, used)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, used)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectFields(cmd, used)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectFields(arg, used)
+		}
+	case *parse.FieldNode:
+		used[n.Ident[0]] = true
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			used[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		collectFields(n.Node, used)
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, used)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, used)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, used)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, used)
+	}
+}
+
+func collectBranch(n *parse.BranchNode, used map[string]bool) {
+	collectFields(n.Pipe, used)
+	collectFields(n.List, used)
+	collectFields(n.ElseList, used)
+}
+
+// overlayFS opens files from primary FS, and from fallback if file does not exist in primary.
+type overlayFS struct {
+	primary  fs.FS
+	fallback fs.FS
+}
+
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.primary.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.fallback.Open(name)
+	}
+	return f, err
+}
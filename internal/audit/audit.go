@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+)
+
+// Actions.
+const (
+	ActionList    = "list"    // forms listing viewed
+	ActionView    = "view"    // form viewed
+	ActionSubmit  = "submit"  // form submitted and stored
+	ActionInvalid = "invalid" // submission rejected by validation or hook
+	ActionDenied  = "denied"  // access denied, see reasons
+	ActionFailed  = "failed"  // submission not stored due to internal error
+)
+
+// Reasons of denied access or failed submission.
+const (
+	ReasonPolicy   = "policy"
+	ReasonCode     = "code"
+	ReasonXSRF     = "xsrf"
+	ReasonCaptcha  = "captcha"
+	ReasonHook     = "hook"
+	ReasonStorage  = "storage"
+	ReasonLimit    = "limit"
+	ReasonRate     = "rate_limit"
+	ReasonSchedule = "schedule"
+	ReasonLink     = "link"
+	ReasonAPIKey   = "api_key"
+	ReasonSpam     = "spam"
+)
+
+// Event of access to forms.
+type Event struct {
+	At         time.Time `json:"at"`
+	Action     string    `json:"action"`
+	Form       string    `json:"form,omitempty"`
+	User       string    `json:"user,omitempty"` // OIDC user
+	Code       string    `json:"code,omitempty"` // fingerprint of access code, see Fingerprint
+	IP         string    `json:"ip"`
+	Submission string    `json:"submission,omitempty"` // submission ID (successful submit only)
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// Sink of audit events. Sinks are append-only.
+type Sink interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Nop sink which records nothing.
+type Nop struct{}
+
+func (Nop) Record(context.Context, Event) error { return nil }
+
+// Multi sends events to all sinks.
+type Multi []Sink
+
+func (m Multi) Record(ctx context.Context, event Event) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Record(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close all sinks which implement io.Closer.
+func (m Multi) Close() error {
+	var errs []error
+	for _, sink := range m {
+		if closer, ok := sink.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Fingerprint of access code: truncated HMAC-SHA256. The same code has the same fingerprint (for the same key), so
+// attempts can be correlated without revealing the code.
+func Fingerprint(key []byte, code string) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// HideCodes replaces access codes in events by fingerprints before passing them to the sink.
+// If key is empty, random key is used, so fingerprints can be correlated only within the process.
+func HideCodes(sink Sink, key []byte) Sink {
+	if _, ok := sink.(*hiddenCodes); ok {
+		return sink
+	}
+	if len(key) == 0 {
+		key = make([]byte, sha256.Size)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			panic(err)
+		}
+	}
+	return &hiddenCodes{sink: sink, key: key}
+}
+
+type hiddenCodes struct {
+	sink Sink
+	key  []byte
+}
+
+func (hc *hiddenCodes) Record(ctx context.Context, event Event) error {
+	if event.Code != "" {
+		event.Code = Fingerprint(hc.key, event.Code)
+	}
+	return hc.sink.Record(ctx, event)
+}
@@ -0,0 +1,102 @@
+package audit_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/audit"
+	"github.com/reddec/web-form/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	now := time.UnixMilli(time.Now().UnixMilli()).UTC()
+	first := audit.Event{At: now, Action: audit.ActionView, Form: "pizza", IP: "127.0.0.1"}
+	second := audit.Event{At: now, Action: audit.ActionDenied, Form: "pizza", Code: "bad", IP: "127.0.0.1", Reason: audit.ReasonCode}
+
+	sink, err := audit.NewFile(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Record(ctx, first))
+	require.NoError(t, sink.Close())
+
+	// file should be appended, not truncated
+	sink, err = audit.NewFile(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Record(ctx, second))
+	require.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var events []audit.Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event audit.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []audit.Event{first, second}, events)
+}
+
+func TestDB_sqlite(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	s, err := storage.NewDB(ctx, "sqlite", "file::memory:")
+	require.NoError(t, err)
+	defer s.Close()
+
+	sink, err := audit.NewDB(ctx, s.DB())
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, sink.Record(ctx, audit.Event{At: now, Action: audit.ActionSubmit, Form: "pizza", User: "reddec", IP: "127.0.0.1", Submission: "abc"}))
+
+	var rows []struct {
+		CreatedAt  int64  `db:"created_at"`
+		Action     string `db:"action"`
+		User       string `db:"user_name"`
+		Submission string `db:"submission"`
+	}
+	require.NoError(t, s.DB().SelectContext(ctx, &rows, "SELECT created_at, action, user_name, submission FROM web_form_audit"))
+	require.Len(t, rows, 1)
+	assert.Equal(t, now.UnixMilli(), rows[0].CreatedAt)
+	assert.Equal(t, audit.ActionSubmit, rows[0].Action)
+	assert.Equal(t, "reddec", rows[0].User)
+	assert.Equal(t, "abc", rows[0].Submission)
+}
+
+func TestHideCodes(t *testing.T) {
+	ctx := context.Background()
+	var recorded []audit.Event
+	sink := audit.HideCodes(recorderFunc(func(event audit.Event) { recorded = append(recorded, event) }), []byte("key"))
+	require.Same(t, sink, audit.HideCodes(sink, []byte("other")))
+
+	require.NoError(t, sink.Record(ctx, audit.Event{Action: audit.ActionDenied, Code: "secret"}))
+	require.NoError(t, sink.Record(ctx, audit.Event{Action: audit.ActionView}))
+	require.Len(t, recorded, 2)
+	assert.Equal(t, audit.Fingerprint([]byte("key"), "secret"), recorded[0].Code)
+	assert.NotEqual(t, audit.Fingerprint([]byte("other"), "secret"), recorded[0].Code)
+	assert.Len(t, recorded[0].Code, 16)
+	assert.Empty(t, recorded[1].Code)
+}
+
+type recorderFunc func(event audit.Event)
+
+func (f recorderFunc) Record(_ context.Context, event audit.Event) error {
+	f(event)
+	return nil
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const dbSchema = `
+CREATE TABLE IF NOT EXISTS web_form_audit (
+    created_at BIGINT NOT NULL,
+    action     TEXT   NOT NULL,
+    form       TEXT   NOT NULL DEFAULT '',
+    user_name  TEXT   NOT NULL DEFAULT '',
+    code       TEXT   NOT NULL DEFAULT '',
+    ip         TEXT   NOT NULL DEFAULT '',
+    submission TEXT   NOT NULL DEFAULT '',
+    reason     TEXT   NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS web_form_audit_created_at ON web_form_audit (created_at);
+`
+
+// NewDB creates audit sink in database. Table web_form_audit will be created automatically.
+func NewDB(ctx context.Context, db *sqlx.DB) (*DB, error) {
+	if _, err := db.ExecContext(ctx, dbSchema); err != nil {
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	return &DB{db: db}, nil
+}
+
+type DB struct {
+	db *sqlx.DB
+}
+
+func (d *DB) Record(ctx context.Context, event Event) error {
+	_, err := d.db.ExecContext(ctx, d.db.Rebind(`
+INSERT INTO web_form_audit (created_at, action, form, user_name, code, ip, submission, reason) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		event.At.UnixMilli(), event.Action, event.Form, event.User, event.Code, event.IP, event.Submission, event.Reason)
+	return err
+}
@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// NewFile opens (or creates) file for appending audit events in JSONL format.
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+	return &File{file: f}, nil
+}
+
+type File struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+func (f *File) Record(_ context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	data = append(data, '\n')
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	_, err = f.file.Write(data)
+	return err
+}
+
+func (f *File) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,34 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/reddec/web-form/internal/notifications"
+	"github.com/reddec/web-form/internal/schema"
+)
+
+// NewNotification sends audit events to notification target (webhook, AMQP).
+// Event is passed as .Result in notification context.
+func NewNotification(target notifications.Notification) Sink {
+	return &notification{target: target}
+}
+
+type notification struct {
+	target notifications.Notification
+}
+
+func (n *notification) Record(ctx context.Context, event Event) error {
+	return n.target.Dispatch(ctx, schema.NotifyContext{
+		Submission: event.Submission,
+		Result: map[string]any{
+			"at":         event.At,
+			"action":     event.Action,
+			"form":       event.Form,
+			"user":       event.User,
+			"code":       event.Code,
+			"ip":         event.IP,
+			"submission": event.Submission,
+			"reason":     event.Reason,
+		},
+	})
+}
@@ -0,0 +1,146 @@
+package captcha_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/captcha"
+	"github.com/reddec/web-form/internal/ratelimit"
+	"github.com/reddec/web-form/internal/web"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stand-in for siteverify API: token "good" is valid, score and action are taken from token "good:<score>:<action>".
+func newVerifier(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "secret", request.PostFormValue("secret"))
+		assert.Equal(t, "192.0.2.1", request.PostFormValue("remoteip"))
+		parts := strings.Split(request.PostFormValue("response"), ":")
+		var out = map[string]any{"success": parts[0] == "good"}
+		if len(parts) == 3 {
+			out["score"] = json.Number(parts[1])
+			out["action"] = parts[2]
+		}
+		_ = json.NewEncoder(writer).Encode(out)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func validate(widget web.Captcha, field, token string) bool {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{field: {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.1:1234"
+	_ = req.ParseForm()
+	return widget.Validate(req)
+}
+
+func TestTurnstile(t *testing.T) {
+	srv := newVerifier(t)
+	widget := &captcha.Turnstile{SiteKey: "site", SecretKey: "secret", Timeout: time.Second, VerifyURL: srv.URL}
+	assert.Contains(t, string(widget.Embed()), `data-sitekey="site"`)
+	assert.True(t, validate(widget, "cf-turnstile-response", "good"))
+	assert.False(t, validate(widget, "cf-turnstile-response", "bad"))
+}
+
+func TestHCaptcha(t *testing.T) {
+	srv := newVerifier(t)
+	widget := &captcha.HCaptcha{SiteKey: "site", SecretKey: "secret", Timeout: time.Second, VerifyURL: srv.URL}
+	assert.Contains(t, string(widget.Embed()), `class="h-captcha" data-sitekey="site"`)
+	assert.True(t, validate(widget, "h-captcha-response", "good"))
+	assert.False(t, validate(widget, "h-captcha-response", "bad"))
+	assert.False(t, validate(widget, "cf-turnstile-response", "good"))
+}
+
+func TestReCaptcha(t *testing.T) {
+	srv := newVerifier(t)
+
+	t.Run("v2", func(t *testing.T) {
+		widget := &captcha.ReCaptcha{SiteKey: "site", SecretKey: "secret", Version: 2, Timeout: time.Second, VerifyURL: srv.URL}
+		assert.Contains(t, string(widget.Embed()), `class="g-recaptcha" data-sitekey="site"`)
+		assert.True(t, validate(widget, "g-recaptcha-response", "good"))
+		assert.False(t, validate(widget, "g-recaptcha-response", "bad"))
+	})
+
+	t.Run("v3", func(t *testing.T) {
+		widget := &captcha.ReCaptcha{SiteKey: "site", SecretKey: "secret", Version: 3, MinScore: 0.5, Action: "submit", Timeout: time.Second, VerifyURL: srv.URL}
+		assert.Contains(t, string(widget.Embed()), `api.js?render=site`)
+		assert.True(t, validate(widget, "g-recaptcha-response", "good:0.9:submit"))
+		assert.True(t, validate(widget, "g-recaptcha-response", "good:0.5:submit"))
+		assert.False(t, validate(widget, "g-recaptcha-response", "good:0.3:submit"), "low score")
+		assert.False(t, validate(widget, "g-recaptcha-response", "good:0.9:login"), "other action")
+		assert.False(t, validate(widget, "g-recaptcha-response", "bad:0.9:submit"))
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		widget := &captcha.ReCaptcha{SecretKey: "secret", Timeout: time.Second, VerifyURL: "http://127.0.0.1:1"}
+		assert.False(t, validate(widget, "g-recaptcha-response", "good"))
+	})
+}
+
+func TestPoW(t *testing.T) {
+	ctx := context.Background()
+	config := captcha.PoWConfig{Key: "0123456789abcdef", Difficulty: 8, TTL: time.Minute}
+
+	_, err := captcha.NewPoW(captcha.PoWConfig{Key: "short", Difficulty: 8}, nil)
+	require.ErrorIs(t, err, captcha.ErrShortKey)
+	_, err = captcha.NewPoW(captcha.PoWConfig{Difficulty: 33}, nil)
+	require.ErrorIs(t, err, captcha.ErrDifficulty)
+
+	pow, err := captcha.NewPoW(config, ratelimit.NewMemory())
+	require.NoError(t, err)
+
+	challenge, err := pow.Challenge()
+	require.NoError(t, err)
+	solution := solve(challenge, config.Difficulty)
+
+	assert.ErrorIs(t, pow.Check(ctx, challenge, "x"), captcha.ErrUnsolved)
+	assert.ErrorIs(t, pow.Check(ctx, challenge+"x", solution), captcha.ErrInvalidChallenge)
+	assert.ErrorIs(t, pow.Check(ctx, strings.Replace(challenge, ".8.", ".1.", 1), solution), captcha.ErrInvalidChallenge)
+	require.NoError(t, pow.Check(ctx, challenge, solution))
+	assert.ErrorIs(t, pow.Check(ctx, challenge, solution), captcha.ErrReplay)
+
+	// challenge signed by other key
+	other, err := captcha.NewPoW(captcha.PoWConfig{Difficulty: 8, TTL: time.Minute}, nil)
+	require.NoError(t, err)
+	foreign, err := other.Challenge()
+	require.NoError(t, err)
+	assert.ErrorIs(t, pow.Check(ctx, foreign, solve(foreign, config.Difficulty)), captcha.ErrInvalidChallenge)
+
+	// expired
+	expiredPoW, err := captcha.NewPoW(captcha.PoWConfig{Key: config.Key, Difficulty: 8, TTL: -time.Second}, nil)
+	require.NoError(t, err)
+	expired, err := expiredPoW.Challenge()
+	require.NoError(t, err)
+	assert.ErrorIs(t, pow.Check(ctx, expired, solve(expired, config.Difficulty)), captcha.ErrExpired)
+
+	// form
+	assert.Contains(t, string(pow.Embed()), `class="pow-captcha"`)
+	challenge, err = pow.Challenge()
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{
+		"pow-challenge": {challenge},
+		"pow-solution":  {solve(challenge, config.Difficulty)},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	require.NoError(t, req.ParseForm())
+	assert.True(t, pow.Validate(req))
+	assert.False(t, pow.Validate(req))
+}
+
+func solve(challenge string, difficulty int) string {
+	for i := 0; ; i++ {
+		if solution := strconv.Itoa(i); captcha.Solves(challenge, solution, difficulty) {
+			return solution
+		}
+	}
+}
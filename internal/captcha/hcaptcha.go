@@ -0,0 +1,36 @@
+package captcha
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/reddec/web-form/internal/web"
+)
+
+type HCaptcha struct {
+	SiteKey   string        `long:"site-key" env:"SITE_KEY" description:"Widget access key"`
+	SecretKey string        `long:"secret-key" env:"SECRET_KEY" description:"Server side secret key"`
+	Timeout   time.Duration `long:"timeout" env:"TIMEOUT" description:"Validation request timeout" default:"3s"`
+	VerifyURL string        `long:"verify-url" env:"VERIFY_URL" description:"Validation endpoint" default:"https://api.hcaptcha.com/siteverify"`
+	Client    *http.Client  `no-flag:"true"` // optional client for validation requests, default is http.DefaultClient
+}
+
+func (widget *HCaptcha) Embed() template.HTML {
+	//nolint:gosec
+	return template.HTML(`
+   <div class="h-captcha" data-sitekey="` + url.QueryEscape(widget.SiteKey) + `"></div>
+   <script src="https://js.hcaptcha.com/1/api.js" async defer></script>
+`)
+}
+
+func (widget *HCaptcha) Validate(form *http.Request) bool {
+	response, err := verify(form.Context(), widget.Client, widget.Timeout, widget.VerifyURL, widget.SecretKey, form.Form.Get("h-captcha-response"), web.GetClientIP(form))
+	if err != nil {
+		slog.Error("failed check hcaptcha", "error", err)
+		return false
+	}
+	return response.Success
+}
@@ -0,0 +1,180 @@
+package captcha
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reddec/web-form/internal/ratelimit"
+)
+
+const (
+	NamePoW       = "pow"
+	powMinKeySize = 16
+	powNonceSize  = 16
+)
+
+var (
+	ErrShortKey         = errors.New("proof-of-work key should be at least 16 bytes")
+	ErrDifficulty       = errors.New("proof-of-work difficulty should be between 1 and 32 bits")
+	ErrInvalidChallenge = errors.New("invalid challenge")
+	ErrExpired          = errors.New("challenge expired")
+	ErrReplay           = errors.New("challenge already used")
+	ErrUnsolved         = errors.New("challenge not solved")
+)
+
+type PoWConfig struct {
+	Enable     bool          `long:"enable" env:"ENABLE" description:"Enable self-hosted proof-of-work captcha"`
+	Key        string        `long:"key" env:"KEY" description:"Secret key (at least 16 bytes) to sign challenges. If not set - random key is used, which is not shared between instances"`
+	Difficulty int           `long:"difficulty" env:"DIFFICULTY" description:"Number of leading zero bits of solution hash, each bit doubles client work" default:"16"`
+	TTL        time.Duration `long:"ttl" env:"TTL" description:"Challenge lifetime" default:"10m"`
+}
+
+// NewPoW creates proof-of-work captcha. Client finds counter so SHA-256 of challenge, colon and counter has
+// required number of leading zero bits. Challenges are signed by HMAC and expire after TTL. Used challenges are
+// registered in the limiter (shared between instances for Redis backend) to prevent replay.
+func NewPoW(config PoWConfig, seen ratelimit.Limiter) (*PoW, error) {
+	key := []byte(config.Key)
+	if len(key) == 0 {
+		key = make([]byte, sha256.Size)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("generate key: %w", err)
+		}
+	}
+	if len(key) < powMinKeySize {
+		return nil, ErrShortKey
+	}
+	if config.Difficulty < 1 || config.Difficulty > 32 {
+		return nil, ErrDifficulty
+	}
+	if seen == nil {
+		seen = ratelimit.NewMemory()
+	}
+	return &PoW{
+		key:        key,
+		difficulty: config.Difficulty,
+		ttl:        config.TTL,
+		seen:       seen,
+		now:        time.Now,
+	}, nil
+}
+
+type PoW struct {
+	key        []byte
+	difficulty int
+	ttl        time.Duration
+	seen       ratelimit.Limiter
+	now        func() time.Time
+}
+
+func (widget *PoW) Embed() template.HTML {
+	challenge, err := widget.Challenge()
+	if err != nil {
+		slog.Error("failed create proof-of-work challenge", "error", err)
+		return ""
+	}
+	//nolint:gosec
+	return template.HTML(`
+   <div class="pow-captcha" data-challenge="` + template.HTMLEscapeString(challenge) + `" data-difficulty="` + strconv.Itoa(widget.difficulty) + `">
+       <input type="hidden" name="pow-challenge" value="` + template.HTMLEscapeString(challenge) + `"/>
+       <input type="hidden" name="pow-solution" value=""/>
+       <p class="help pow-status">Checking your browser...</p>
+   </div>
+   <script src="../static/js/pow.js" defer></script>
+`)
+}
+
+func (widget *PoW) Validate(form *http.Request) bool {
+	err := widget.Check(form.Context(), form.Form.Get("pow-challenge"), form.Form.Get("pow-solution"))
+	if err != nil {
+		slog.Info("proof-of-work captcha rejected", "error", err)
+		return false
+	}
+	return true
+}
+
+// Challenge creates new signed challenge: <nonce>.<expires at unix>.<difficulty>.<signature>.
+func (widget *PoW) Challenge() (string, error) {
+	var nonce [powNonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	payload := hex.EncodeToString(nonce[:]) + "." + strconv.FormatInt(widget.now().Add(widget.ttl).Unix(), 10) + "." + strconv.Itoa(widget.difficulty)
+	return payload + "." + widget.sign(payload), nil
+}
+
+// Check solution of the challenge. Each challenge can be used only once.
+func (widget *PoW) Check(ctx context.Context, challenge string, solution string) error {
+	payload, signature, ok := cutLast(challenge, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(widget.sign(payload))) {
+		return ErrInvalidChallenge
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return ErrInvalidChallenge
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrInvalidChallenge
+	}
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return ErrInvalidChallenge
+	}
+	ttl := time.Unix(expiresAt, 0).Sub(widget.now())
+	if ttl <= 0 {
+		return ErrExpired
+	}
+	if _, err := strconv.ParseUint(solution, 10, 64); err != nil || !Solves(challenge, solution, difficulty) {
+		return ErrUnsolved
+	}
+	// challenge is kept as used till it expires
+	hits, err := widget.seen.Hit(ctx, "pow:"+parts[0], ttl)
+	if err != nil {
+		return fmt.Errorf("register challenge: %w", err)
+	}
+	if hits > 1 {
+		return ErrReplay
+	}
+	return nil
+}
+
+func (widget *PoW) sign(payload string) string {
+	mac := hmac.New(sha256.New, widget.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Solves returns true if SHA-256 of challenge, colon and solution has at least difficulty leading zero bits.
+func Solves(challenge string, solution string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + solution))
+	var zeros int
+	for i := 0; i < len(sum) && zeros < difficulty; i += 4 {
+		word := binary.BigEndian.Uint32(sum[i:])
+		zeros += bits.LeadingZeros32(word)
+		if word != 0 {
+			break
+		}
+	}
+	return zeros >= difficulty
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,71 @@
+package captcha
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/reddec/web-form/internal/web"
+)
+
+// ReCaptcha is Google reCAPTCHA. Version 2 shows checkbox widget, version 3 is invisible and validated by score.
+type ReCaptcha struct {
+	SiteKey   string        `long:"site-key" env:"SITE_KEY" description:"Widget access key"`
+	SecretKey string        `long:"secret-key" env:"SECRET_KEY" description:"Server side secret key"`
+	Version   int           `long:"version" env:"VERSION" description:"reCAPTCHA version" default:"2" choice:"2" choice:"3"`
+	MinScore  float64       `long:"min-score" env:"MIN_SCORE" description:"Minimal score (0..1) for reCAPTCHA v3" default:"0.5"`
+	Action    string        `long:"action" env:"ACTION" description:"Expected action for reCAPTCHA v3" default:"submit"`
+	Timeout   time.Duration `long:"timeout" env:"TIMEOUT" description:"Validation request timeout" default:"3s"`
+	VerifyURL string        `long:"verify-url" env:"VERIFY_URL" description:"Validation endpoint" default:"https://www.google.com/recaptcha/api/siteverify"`
+	Client    *http.Client  `no-flag:"true"` // optional client for validation requests, default is http.DefaultClient
+}
+
+func (widget *ReCaptcha) Embed() template.HTML {
+	if widget.Version != 3 {
+		//nolint:gosec
+		return template.HTML(`
+   <div class="g-recaptcha" data-sitekey="` + url.QueryEscape(widget.SiteKey) + `"></div>
+   <script src="https://www.google.com/recaptcha/api.js" async defer></script>
+`)
+	}
+	// v3 is invisible: token is requested right before submit since it expires in 2 minutes
+	siteKey := template.JSEscapeString(widget.SiteKey)
+	//nolint:gosec
+	return template.HTML(`
+   <input type="hidden" name="g-recaptcha-response" id="g-recaptcha-response"/>
+   <script src="https://www.google.com/recaptcha/api.js?render=` + url.QueryEscape(widget.SiteKey) + `"></script>
+   <script>
+       document.getElementById("g-recaptcha-response").form.addEventListener("submit", function (event) {
+           var form = event.target;
+           event.preventDefault();
+           grecaptcha.ready(function () {
+               grecaptcha.execute("` + siteKey + `", {action: "` + template.JSEscapeString(widget.Action) + `"}).then(function (token) {
+                   document.getElementById("g-recaptcha-response").value = token;
+                   form.submit();
+               });
+           });
+       });
+   </script>
+`)
+}
+
+func (widget *ReCaptcha) Validate(form *http.Request) bool {
+	response, err := verify(form.Context(), widget.Client, widget.Timeout, widget.VerifyURL, widget.SecretKey, form.Form.Get("g-recaptcha-response"), web.GetClientIP(form))
+	if err != nil {
+		slog.Error("failed check recaptcha", "error", err)
+		return false
+	}
+	if !response.Success {
+		return false
+	}
+	if widget.Version != 3 {
+		return true
+	}
+	if widget.Action != "" && response.Action != widget.Action {
+		slog.Warn("recaptcha action mismatch", "expected", widget.Action, "actual", response.Action)
+		return false
+	}
+	return response.Score >= widget.MinScore
+}
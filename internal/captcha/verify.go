@@ -0,0 +1,64 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Names of captcha providers for form definitions.
+const (
+	NameTurnstile = "turnstile"
+	NameHCaptcha  = "hcaptcha"
+	NameReCaptcha = "recaptcha"
+)
+
+// verification response (siteverify API) is the same for all supported providers.
+type verifyResponse struct {
+	Success    bool     `json:"success"`
+	Score      float64  `json:"score"`       // reCAPTCHA v3 only
+	Action     string   `json:"action"`      // reCAPTCHA v3 only
+	ErrorCodes []string `json:"error-codes"` //nolint:tagliatelle
+}
+
+func verify(ctx context.Context, client *http.Client, timeout time.Duration, verifyURL string, secret string, response string, remoteIP string) (*verifyResponse, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var formFields = make(url.Values)
+	formFields.Add("secret", secret)
+	formFields.Add("response", response)
+	formFields.Add("remoteip", remoteIP)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, verifyURL, strings.NewReader(formFields.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	var out verifyResponse
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &out, nil
+}
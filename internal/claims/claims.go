@@ -0,0 +1,107 @@
+// Package claims maps OIDC claims to credentials.
+package claims
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reddec/web-form/internal/schema"
+)
+
+// Mapping of claims to credentials. Each claim is a path: top-level claim name (including namespaced names like
+// https://example.com/groups) or dot-separated path for nested claims, for example realm_access.roles.
+type Mapping struct {
+	User   []string // candidates for user name, first non-empty is used
+	Email  string   // claim with email
+	Groups []string // claims with groups, all values are merged
+}
+
+// Credentials from the claims.
+func (m *Mapping) Credentials(claims map[string]any) *schema.Credentials {
+	var user string
+	for _, path := range m.User {
+		if user = toString(Lookup(claims, path)); user != "" {
+			break
+		}
+	}
+
+	var groups []string
+	for _, path := range m.Groups {
+		groups = appendUnique(groups, toStrings(Lookup(claims, path))...)
+	}
+
+	return &schema.Credentials{
+		User:   user,
+		Email:  toString(Lookup(claims, m.Email)),
+		Groups: groups,
+		Claims: claims,
+	}
+}
+
+// Lookup value by path. Exact claim name has priority over nested path. Returns nil if nothing found.
+func Lookup(claims map[string]any, path string) any {
+	if path == "" {
+		return nil
+	}
+	if v, ok := claims[path]; ok {
+		return v
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '.' {
+			continue
+		}
+		if nested, ok := claims[path[:i]].(map[string]any); ok {
+			if v := Lookup(nested, path[i+1:]); v != nil {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toStrings(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		var ans = make([]string, 0, len(v))
+		for _, item := range v {
+			if s := toString(item); s != "" {
+				ans = append(ans, s)
+			}
+		}
+		return ans
+	case string:
+		// some providers return space or comma separated list
+		return strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	default:
+		return []string{toString(v)}
+	}
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, existent := range list {
+			if existent == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,38 @@
+package claims_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/reddec/web-form/internal/claims"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapping_Credentials(t *testing.T) {
+	const token = `{
+  "sub": "1234",
+  "email": "alice@example.com",
+  "roles": ["admin"],
+  "realm_access": {"roles": ["dev", "admin"]},
+  "https://example.com/groups": "ops qa",
+  "department": "R&D"
+}`
+	var values map[string]any
+	require.NoError(t, json.Unmarshal([]byte(token), &values))
+
+	mapping := claims.Mapping{
+		User:   []string{"preferred_username", "email", "sub"},
+		Email:  "email",
+		Groups: []string{"groups", "roles", "realm_access.roles", "https://example.com/groups"},
+	}
+	creds := mapping.Credentials(values)
+	assert.Equal(t, "alice@example.com", creds.User)
+	assert.Equal(t, "alice@example.com", creds.Email)
+	assert.Equal(t, []string{"admin", "dev", "ops", "qa"}, creds.Groups)
+	assert.Equal(t, "R&D", creds.Claims["department"])
+
+	assert.Nil(t, claims.Lookup(values, "realm_access.missing"))
+	assert.Nil(t, claims.Lookup(values, ""))
+}
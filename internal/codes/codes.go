@@ -0,0 +1,116 @@
+package codes
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("code not found")
+	ErrInvalid  = errors.New("code expired, revoked or used up")
+	ErrBadMint  = errors.New("invalid mint request")
+)
+
+const (
+	codeSize = 10    // bytes of entropy, 16 characters in base32
+	maxMint  = 10000 // maximum number of codes minted at once
+)
+
+// Code issued for the form. Only digest of code is stored, so plain code is known only after minting.
+type Code struct {
+	Form      string            `json:"form"`
+	Code      string            `json:"code,omitempty"`       // plain code, empty if not known
+	Digest    string            `json:"digest"`               // SHA-256 of code, see Digest
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"` // optional expiration time
+	MaxUses   int               `json:"max_uses"`             // maximum number of submissions, zero means unlimited
+	Uses      int               `json:"uses"`                 // number of successful submissions
+	Values    map[string]string `json:"values,omitempty"`     // values bound to the code, available in templates
+	Revoked   bool              `json:"revoked"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Valid returns true if code is not revoked, not expired and not used up.
+func (c *Code) Valid(now time.Time) bool {
+	return !c.Revoked &&
+		(c.ExpiresAt == nil || now.Before(*c.ExpiresAt)) &&
+		(c.MaxUses == 0 || c.Uses < c.MaxUses)
+}
+
+// Mint request.
+type Mint struct {
+	Count     int                 `json:"count"`                // number of codes, ignored if values set
+	ExpiresAt *time.Time          `json:"expires_at,omitempty"` // optional expiration time
+	MaxUses   int                 `json:"max_uses"`             // maximum number of submissions per code, zero means unlimited
+	Values    []map[string]string `json:"values,omitempty"`     // optional values per code, one code per item
+}
+
+// Validate mint request.
+func (m *Mint) Validate(now time.Time) error {
+	count := m.Count
+	if len(m.Values) > 0 {
+		count = len(m.Values)
+	}
+	if count <= 0 || count > maxMint {
+		return fmt.Errorf("%w: number of codes should be between 1 and %d", ErrBadMint, maxMint)
+	}
+	if m.MaxUses < 0 {
+		return fmt.Errorf("%w: negative max uses", ErrBadMint)
+	}
+	if m.ExpiresAt != nil && !m.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: expiration time in the past", ErrBadMint)
+	}
+	return nil
+}
+
+// Codes generates new codes for the form. Panics if crypto generator is not available.
+func (m *Mint) Codes(form string, now time.Time) []Code {
+	count := m.Count
+	if len(m.Values) > 0 {
+		count = len(m.Values)
+	}
+	var ans = make([]Code, 0, count)
+	for i := 0; i < count; i++ {
+		plain := Generate()
+		code := Code{
+			Form:      form,
+			Code:      plain,
+			Digest:    Digest(plain),
+			ExpiresAt: m.ExpiresAt,
+			MaxUses:   m.MaxUses,
+			CreatedAt: now,
+		}
+		if len(m.Values) > 0 {
+			code.Values = m.Values[i]
+		}
+		ans = append(ans, code)
+	}
+	return ans
+}
+
+// Generate random code. Panics if crypto generator is not available.
+func Generate() string {
+	var data [codeSize]byte
+	if _, err := io.ReadFull(rand.Reader, data[:]); err != nil {
+		panic(err)
+	}
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(data[:]))
+}
+
+// Digest of code (hex-encoded SHA-256) as it is stored. Codes have enough entropy, so plain hash is sufficient.
+func Digest(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// isDigest returns true if value looks like digest of code.
+func isDigest(value string) bool {
+	_, err := hex.DecodeString(value)
+	return err == nil && len(value) == 2*sha256.Size
+}
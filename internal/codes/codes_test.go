@@ -0,0 +1,113 @@
+package codes_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/codes"
+	"github.com/reddec/web-form/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestDB_sqlite(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	s, err := storage.NewDB(ctx, "sqlite", "file::memory:")
+	require.NoError(t, err)
+	defer s.Close()
+
+	store, err := codes.NewDB(ctx, s.DB())
+	require.NoError(t, err)
+
+	t.Run("max uses", func(t *testing.T) {
+		list, err := store.Mint(ctx, "once", codes.Mint{Count: 2, MaxUses: 1})
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.NotEqual(t, list[0].Code, list[1].Code)
+		assert.Equal(t, codes.Digest(list[0].Code), list[0].Digest)
+
+		code := list[0].Code
+		require.NoError(t, store.Consume(ctx, "once", code))
+		require.ErrorIs(t, store.Consume(ctx, "once", code), codes.ErrInvalid)
+
+		// refunded use can be consumed again
+		require.NoError(t, store.Refund(ctx, "once", code))
+		require.NoError(t, store.Consume(ctx, "once", code))
+
+		// codes are bound to form
+		require.ErrorIs(t, store.Consume(ctx, "other", list[1].Code), codes.ErrInvalid)
+		_, err = store.Get(ctx, "other", list[1].Code)
+		require.ErrorIs(t, err, codes.ErrNotFound)
+	})
+
+	t.Run("expiration", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Second)
+		list, err := store.Mint(ctx, "expired", codes.Mint{Count: 1, ExpiresAt: &expiresAt})
+		require.NoError(t, err)
+
+		code, err := store.Get(ctx, "expired", list[0].Code)
+		require.NoError(t, err)
+		assert.False(t, code.Valid(time.Now()))
+		require.ErrorIs(t, store.Consume(ctx, "expired", list[0].Code), codes.ErrInvalid)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		list, err := store.Mint(ctx, "revoke", codes.Mint{Values: []map[string]string{{"name": "Alice"}, {"name": "Bob"}, {"name": "Eve"}}})
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+
+		n, err := store.Revoke(ctx, "revoke", []string{list[0].Code})
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		require.ErrorIs(t, store.Consume(ctx, "revoke", list[0].Code), codes.ErrInvalid)
+		require.NoError(t, store.Consume(ctx, "revoke", list[1].Code))
+
+		// only digests are stored
+		all, err := store.List(ctx, "revoke")
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+		for _, code := range all {
+			assert.Empty(t, code.Code)
+			assert.NotEmpty(t, code.Digest)
+		}
+
+		// listed codes can be revoked by digest
+		n, err = store.Revoke(ctx, "revoke", []string{codes.Digest(list[2].Code)})
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		n, err = store.RevokeAll(ctx, "revoke")
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		all, err = store.List(ctx, "revoke")
+		require.NoError(t, err)
+		for _, code := range all {
+			assert.True(t, code.Revoked)
+		}
+		code, err := store.Get(ctx, "revoke", list[1].Code)
+		require.NoError(t, err)
+		assert.Equal(t, list[1].Code, code.Code)
+		assert.Equal(t, map[string]string{"name": "Bob"}, code.Values)
+		assert.Equal(t, 1, code.Uses)
+	})
+}
+
+func TestDigest(t *testing.T) {
+	assert.Equal(t, "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", codes.Digest("secret"))
+}
+
+func TestMint_Validate(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	assert.NoError(t, (&codes.Mint{Count: 1}).Validate(now))
+	assert.NoError(t, (&codes.Mint{Values: []map[string]string{{}}}).Validate(now))
+	assert.ErrorIs(t, (&codes.Mint{}).Validate(now), codes.ErrBadMint)
+	assert.ErrorIs(t, (&codes.Mint{Count: 1, MaxUses: -1}).Validate(now), codes.ErrBadMint)
+	assert.ErrorIs(t, (&codes.Mint{Count: 1, ExpiresAt: &past}).Validate(now), codes.ErrBadMint)
+}
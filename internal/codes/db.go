@@ -0,0 +1,199 @@
+package codes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const dbSchema = `
+CREATE TABLE IF NOT EXISTS web_form_codes (
+    form       TEXT    NOT NULL,
+    digest     TEXT    NOT NULL,
+    expires_at BIGINT  NOT NULL DEFAULT 0,
+    max_uses   INTEGER NOT NULL DEFAULT 0,
+    uses       INTEGER NOT NULL DEFAULT 0,
+    params     TEXT    NOT NULL DEFAULT '{}',
+    revoked    INTEGER NOT NULL DEFAULT 0,
+    created_at BIGINT  NOT NULL,
+    PRIMARY KEY (form, digest)
+);
+`
+
+// NewDB creates codes store in database. Table web_form_codes will be created automatically.
+//
+// Codes are stored as digests (see Digest): plain codes are returned only by Mint.
+func NewDB(ctx context.Context, db *sqlx.DB) (*DB, error) {
+	if _, err := db.ExecContext(ctx, dbSchema); err != nil {
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	return &DB{db: db}, nil
+}
+
+type DB struct {
+	db *sqlx.DB
+}
+
+type codeRow struct {
+	Form      string `db:"form"`
+	Digest    string `db:"digest"`
+	ExpiresAt int64  `db:"expires_at"`
+	MaxUses   int    `db:"max_uses"`
+	Uses      int    `db:"uses"`
+	Params    string `db:"params"`
+	Revoked   int    `db:"revoked"`
+	CreatedAt int64  `db:"created_at"`
+}
+
+func (row *codeRow) toCode() (Code, error) {
+	code := Code{
+		Form:      row.Form,
+		Digest:    row.Digest,
+		MaxUses:   row.MaxUses,
+		Uses:      row.Uses,
+		Revoked:   row.Revoked != 0,
+		CreatedAt: time.UnixMilli(row.CreatedAt),
+	}
+	if row.ExpiresAt > 0 {
+		expiresAt := time.UnixMilli(row.ExpiresAt)
+		code.ExpiresAt = &expiresAt
+	}
+	if err := json.Unmarshal([]byte(row.Params), &code.Values); err != nil {
+		return code, fmt.Errorf("decode values of code: %w", err)
+	}
+	return code, nil
+}
+
+// Mint generates and saves new codes for the form. Returned codes are the only place where plain codes are available.
+func (d *DB) Mint(ctx context.Context, form string, mint Mint) ([]Code, error) {
+	list := mint.Codes(form, time.Now())
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, code := range list {
+		params, err := json.Marshal(code.Values)
+		if err != nil {
+			return nil, fmt.Errorf("encode values: %w", err)
+		}
+		var expiresAt int64
+		if code.ExpiresAt != nil {
+			expiresAt = code.ExpiresAt.UnixMilli()
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(`
+INSERT INTO web_form_codes (form, digest, expires_at, max_uses, params, created_at) VALUES (?, ?, ?, ?, ?, ?)`),
+			code.Form, code.Digest, expiresAt, code.MaxUses, string(params), code.CreatedAt.UnixMilli())
+		if err != nil {
+			return nil, fmt.Errorf("save code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return list, nil
+}
+
+// Get code by plain value. Returns ErrNotFound if code doesn't exist.
+func (d *DB) Get(ctx context.Context, form string, code string) (*Code, error) {
+	var row codeRow
+	err := d.db.GetContext(ctx, &row, d.db.Rebind(`
+SELECT form, digest, expires_at, max_uses, uses, params, revoked, created_at FROM web_form_codes WHERE form = ? AND digest = ?`),
+		form, Digest(code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get code: %w", err)
+	}
+	ans, err := row.toCode()
+	ans.Code = code
+	return &ans, err
+}
+
+// List all codes of the form. Plain codes are not available, only digests.
+func (d *DB) List(ctx context.Context, form string) ([]Code, error) {
+	var rows []codeRow
+	err := d.db.SelectContext(ctx, &rows, d.db.Rebind(`
+SELECT form, digest, expires_at, max_uses, uses, params, revoked, created_at FROM web_form_codes WHERE form = ? ORDER BY created_at, digest`),
+		form)
+	if err != nil {
+		return nil, fmt.Errorf("list codes: %w", err)
+	}
+	var ans = make([]Code, 0, len(rows))
+	for _, row := range rows {
+		code, err := row.toCode()
+		if err != nil {
+			return nil, err
+		}
+		ans = append(ans, code)
+	}
+	return ans, nil
+}
+
+// Consume one use of the code atomically. Returns ErrInvalid if code doesn't exist or not valid.
+func (d *DB) Consume(ctx context.Context, form string, code string) error {
+	res, err := d.db.ExecContext(ctx, d.db.Rebind(`
+UPDATE web_form_codes SET uses = uses + 1 
+WHERE form = ? AND digest = ? AND revoked = 0 AND (expires_at = 0 OR expires_at > ?) AND (max_uses = 0 OR uses < max_uses)`),
+		form, Digest(code), time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("consume code: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("consume code: %w", err)
+	}
+	if n == 0 {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// Refund previously consumed use of the code (ex: if submission was not stored).
+func (d *DB) Refund(ctx context.Context, form string, code string) error {
+	_, err := d.db.ExecContext(ctx, d.db.Rebind(`UPDATE web_form_codes SET uses = uses - 1 WHERE form = ? AND digest = ? AND uses > 0`), form, Digest(code))
+	return err
+}
+
+// Revoke codes of the form. Each item could be plain code or its digest (as returned by List).
+// Returns number of revoked codes.
+func (d *DB) Revoke(ctx context.Context, form string, codes []string) (int, error) {
+	if len(codes) == 0 {
+		return 0, nil
+	}
+	var digests = make([]string, 0, 2*len(codes))
+	for _, code := range codes {
+		digests = append(digests, Digest(code))
+		if isDigest(code) {
+			digests = append(digests, code)
+		}
+	}
+	query, args, err := sqlx.In(`UPDATE web_form_codes SET revoked = 1 WHERE revoked = 0 AND form = ? AND digest IN (?)`, form, digests)
+	if err != nil {
+		return 0, fmt.Errorf("build query: %w", err)
+	}
+	return d.exec(ctx, d.db.Rebind(query), args...)
+}
+
+// RevokeAll codes of the form. Returns number of revoked codes.
+func (d *DB) RevokeAll(ctx context.Context, form string) (int, error) {
+	return d.exec(ctx, d.db.Rebind(`UPDATE web_form_codes SET revoked = 1 WHERE revoked = 0 AND form = ?`), form)
+}
+
+func (d *DB) exec(ctx context.Context, query string, args ...any) (int, error) {
+	res, err := d.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("revoke codes: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,99 @@
+package codes
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/reddec/web-form/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxBodySize = 1 << 20 // maximum size of request payload
+
+// Revoke request.
+type Revoke struct {
+	Codes []string `json:"codes"` // plain codes or digests
+}
+
+// Handler exposes codes management as JSON API. Expects form name as {form} chi URL parameter.
+// Only forms from the provided set are managed.
+//
+//	GET    - list codes
+//	POST   - mint codes (body is Mint)
+//	DELETE - revoke codes (body is Revoke) or all codes with query ?all=true
+func Handler(store *DB, forms utils.Set[string]) http.Handler {
+	mux := chi.NewRouter()
+	mux.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if !forms.Has(chi.URLParam(request, "form")) {
+				writer.WriteHeader(http.StatusNotFound)
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
+	})
+	mux.Get("/", func(writer http.ResponseWriter, request *http.Request) {
+		form := chi.URLParam(request, "form")
+		list, err := store.List(request.Context(), form)
+		if err != nil {
+			slog.Error("failed list codes", "form", form, "error", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		reply(writer, list)
+	})
+	mux.Post("/", func(writer http.ResponseWriter, request *http.Request) {
+		form := chi.URLParam(request, "form")
+		var mint Mint
+		if err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, maxBodySize)).Decode(&mint); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := mint.Validate(time.Now()); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		list, err := store.Mint(request.Context(), form, mint)
+		if err != nil {
+			slog.Error("failed mint codes", "form", form, "error", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		slog.Info("codes minted", "form", form, "count", len(list))
+		reply(writer, list)
+	})
+	mux.Delete("/", func(writer http.ResponseWriter, request *http.Request) {
+		form := chi.URLParam(request, "form")
+		var (
+			revoked int
+			err     error
+		)
+		if all, _ := strconv.ParseBool(request.URL.Query().Get("all")); all {
+			revoked, err = store.RevokeAll(request.Context(), form)
+		} else {
+			var payload Revoke
+			if err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, maxBodySize)).Decode(&payload); err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			revoked, err = store.Revoke(request.Context(), form, payload.Codes)
+		}
+		if err != nil {
+			slog.Error("failed revoke codes", "form", form, "error", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		slog.Info("codes revoked", "form", form, "count", revoked)
+		reply(writer, map[string]int{"revoked": revoked})
+	})
+	return mux
+}
+
+func reply(writer http.ResponseWriter, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(writer).Encode(value)
+}
@@ -0,0 +1,80 @@
+package deliveries
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const dbSchema = `
+CREATE TABLE IF NOT EXISTS web_form_deliveries (
+    submission  TEXT    NOT NULL,
+    form        TEXT    NOT NULL,
+    kind        TEXT    NOT NULL,
+    destination TEXT    NOT NULL,
+    attempt     INTEGER NOT NULL,
+    code        INTEGER NOT NULL DEFAULT 0,
+    error       TEXT    NOT NULL DEFAULT '',
+    latency     BIGINT  NOT NULL,
+    created_at  BIGINT  NOT NULL
+);
+CREATE INDEX IF NOT EXISTS web_form_deliveries_submission ON web_form_deliveries (submission);
+`
+
+// NewDB creates delivery log in database. Table web_form_deliveries will be created automatically.
+func NewDB(ctx context.Context, db *sqlx.DB) (*DB, error) {
+	if _, err := db.ExecContext(ctx, dbSchema); err != nil {
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	return &DB{db: db}, nil
+}
+
+type DB struct {
+	db *sqlx.DB
+}
+
+func (d *DB) Record(ctx context.Context, attempt Attempt) error {
+	_, err := d.db.ExecContext(ctx, d.db.Rebind(`
+INSERT INTO web_form_deliveries (submission, form, kind, destination, attempt, code, error, latency, created_at) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		attempt.Submission, attempt.Form, attempt.Kind, attempt.Destination, attempt.Attempt, attempt.Code,
+		attempt.Error, int64(attempt.Latency), attempt.At.UnixMilli())
+	return err
+}
+
+func (d *DB) List(ctx context.Context, submission string) ([]Attempt, error) {
+	var rows []struct {
+		Submission  string `db:"submission"`
+		Form        string `db:"form"`
+		Kind        string `db:"kind"`
+		Destination string `db:"destination"`
+		Attempt     int    `db:"attempt"`
+		Code        int    `db:"code"`
+		Error       string `db:"error"`
+		Latency     int64  `db:"latency"`
+		CreatedAt   int64  `db:"created_at"`
+	}
+	err := d.db.SelectContext(ctx, &rows, d.db.Rebind(`
+SELECT submission, form, kind, destination, attempt, code, error, latency, created_at 
+FROM web_form_deliveries WHERE submission = ? ORDER BY created_at`), submission)
+	if err != nil {
+		return nil, fmt.Errorf("query attempts: %w", err)
+	}
+	var ans = make([]Attempt, 0, len(rows))
+	for _, row := range rows {
+		ans = append(ans, Attempt{
+			Submission:  row.Submission,
+			Form:        row.Form,
+			Kind:        row.Kind,
+			Destination: row.Destination,
+			Attempt:     row.Attempt,
+			Code:        row.Code,
+			Error:       row.Error,
+			Latency:     time.Duration(row.Latency),
+			At:          time.UnixMilli(row.CreatedAt),
+		})
+	}
+	return ans, nil
+}
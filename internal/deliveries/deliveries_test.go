@@ -0,0 +1,77 @@
+package deliveries_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/deliveries"
+	"github.com/reddec/web-form/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	log := deliveries.NewMemory(2)
+
+	require.NoError(t, log.Record(ctx, deliveries.Attempt{Submission: "1", Attempt: 1, Error: "failed"}))
+	require.NoError(t, log.Record(ctx, deliveries.Attempt{Submission: "1", Attempt: 2}))
+	require.NoError(t, log.Record(ctx, deliveries.Attempt{Submission: "2", Attempt: 1}))
+
+	list, err := log.List(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.False(t, list[0].Delivered())
+	assert.True(t, list[1].Delivered())
+
+	// oldest submission should be evicted
+	require.NoError(t, log.Record(ctx, deliveries.Attempt{Submission: "3", Attempt: 1}))
+	list, err = log.List(ctx, "1")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	list, err = log.List(ctx, "3")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestDB_sqlite(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	s, err := storage.NewDB(ctx, "sqlite", "file::memory:")
+	require.NoError(t, err)
+	defer s.Close()
+
+	log, err := deliveries.NewDB(ctx, s.DB())
+	require.NoError(t, err)
+
+	now := time.UnixMilli(time.Now().UnixMilli())
+	first := deliveries.Attempt{
+		Submission:  "abc",
+		Form:        "pizza",
+		Kind:        deliveries.KindWebhook,
+		Destination: "https://example.com",
+		Attempt:     1,
+		Code:        500,
+		Error:       "non-2xx response code: 500",
+		Latency:     time.Second,
+		At:          now,
+	}
+	second := first
+	second.Attempt = 2
+	second.Code = 200
+	second.Error = ""
+	second.At = now.Add(time.Second)
+
+	require.NoError(t, log.Record(ctx, first))
+	require.NoError(t, log.Record(ctx, second))
+	require.NoError(t, log.Record(ctx, deliveries.Attempt{Submission: "other", At: now}))
+
+	list, err := log.List(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, []deliveries.Attempt{first, second}, list)
+}
@@ -0,0 +1,27 @@
+package deliveries
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler exposes delivery log as JSON. Expects submission ID as {submission} chi URL parameter.
+func Handler(log Log) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		submission := chi.URLParam(request, "submission")
+		list, err := log.List(request.Context(), submission)
+		if err != nil {
+			slog.Error("failed list deliveries", "submission", submission, "error", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if list == nil {
+			list = []Attempt{}
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(list)
+	}
+}
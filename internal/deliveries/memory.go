@@ -0,0 +1,50 @@
+package deliveries
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// NewMemory creates in-memory delivery log which keeps attempts for up to size latest submissions.
+func NewMemory(size int) *Memory {
+	return &Memory{
+		size:        size,
+		submissions: make(map[string][]Attempt),
+	}
+}
+
+type Memory struct {
+	size        int
+	lock        sync.RWMutex
+	order       []string // FIFO of submissions
+	submissions map[string][]Attempt
+}
+
+func (m *Memory) Record(_ context.Context, attempt Attempt) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	list, exists := m.submissions[attempt.Submission]
+	if !exists {
+		m.order = append(m.order, attempt.Submission)
+		m.evict()
+	}
+	m.submissions[attempt.Submission] = append(list, attempt)
+	return nil
+}
+
+func (m *Memory) List(_ context.Context, submission string) ([]Attempt, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return slices.Clone(m.submissions[submission]), nil
+}
+
+func (m *Memory) evict() {
+	if m.size <= 0 {
+		return
+	}
+	for len(m.order) > m.size {
+		delete(m.submissions, m.order[0])
+		m.order = m.order[1:]
+	}
+}
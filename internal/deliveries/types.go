@@ -0,0 +1,48 @@
+package deliveries
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDropped is recorded for notifications which were not delivered due to shutdown.
+var ErrDropped = errors.New("dropped on shutdown")
+
+const (
+	KindWebhook = "webhook"
+	KindAMQP    = "amqp"
+)
+
+// Attempt of notification delivery.
+type Attempt struct {
+	Submission  string        `json:"submission"`     // unique submission ID
+	Form        string        `json:"form"`           // form name
+	Kind        string        `json:"kind"`           // notification kind: webhook or amqp
+	Destination string        `json:"destination"`    // redacted URL for webhooks or exchange/key for AMQP
+	Attempt     int           `json:"attempt"`        // attempt number, starting from 1
+	Code        int           `json:"code,omitempty"` // response code (webhooks only)
+	Error       string        `json:"error,omitempty"`
+	Latency     time.Duration `json:"latency"`
+	At          time.Time     `json:"at"`
+}
+
+// Delivered returns true if attempt was successful.
+func (a *Attempt) Delivered() bool {
+	return a.Error == ""
+}
+
+// Log of delivery attempts.
+type Log interface {
+	// Record single attempt.
+	Record(ctx context.Context, attempt Attempt) error
+	// List all known attempts for the submission ordered by time.
+	List(ctx context.Context, submission string) ([]Attempt, error)
+}
+
+// Nop log which records nothing.
+type Nop struct{}
+
+func (Nop) Record(context.Context, Attempt) error { return nil }
+
+func (Nop) List(context.Context, string) ([]Attempt, error) { return nil, nil }
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/reddec/web-form/internal/ratelimit"
+	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/web"
+)
+
+// apiKeyAuth is result of API key authentication.
+type apiKeyAuth struct {
+	key     *schema.APIKey // matched key, nil if request has no bearer token or token is invalid
+	invalid bool           // bearer token present, but doesn't match any key
+	limited bool           // bearer token present, but not checked due to too many failed attempts
+}
+
+// allowed checks that request has valid key with scope required for the request method.
+// Requests without bearer token are always allowed.
+func (a apiKeyAuth) allowed(method string) bool {
+	if a.invalid || a.limited {
+		return false
+	}
+	return a.key == nil || a.key.Allows(requiredScope(method))
+}
+
+type apiKeyAuthKey struct{}
+
+// Keys authenticates machine clients by API keys. Keys are verified by expensive hashes (bcrypt, argon2id), so
+// verified tokens are cached by SHA-256 (hash is computed at most once per key), and other attempts are counted
+// per client IP before verification.
+type Keys struct {
+	lists    []schema.APIKeys
+	limiter  ratelimit.Limiter
+	rate     ratelimit.Rate
+	verified sync.Map // sha256 of token -> *schema.APIKey
+}
+
+// NewKeys creates authenticator for the lists of keys. Failed attempts are limited by rate if limiter is set.
+func NewKeys(limiter ratelimit.Limiter, rate ratelimit.Rate, lists ...schema.APIKeys) *Keys {
+	return &Keys{lists: lists, limiter: limiter, rate: rate}
+}
+
+// Skip bypasses interactive authorization (OIDC, forward auth, htpasswd) for requests with valid API key.
+// Requests with other bearer tokens (ex: from authenticating proxy) pass interactive authorization as usual.
+func (k *Keys) Skip(interactive func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		secured := interactive(next)
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			auth := k.authenticate(request)
+			if auth.key != nil {
+				next.ServeHTTP(writer, request)
+				return
+			}
+			if auth.invalid || auth.limited {
+				// let engine reuse the result instead of checking token again
+				request = request.WithContext(context.WithValue(request.Context(), apiKeyAuthKey{}, auth))
+			}
+			secured.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// authenticate request by API key from Authorization header.
+func (k *Keys) authenticate(request *http.Request) apiKeyAuth {
+	token, ok := web.BearerToken(request)
+	if !ok {
+		return apiKeyAuth{}
+	}
+	digest := sha256.Sum256([]byte(token))
+	if key, ok := k.verified.Load(digest); ok {
+		return apiKeyAuth{key: key.(*schema.APIKey)}
+	}
+	if auth, ok := request.Context().Value(apiKeyAuthKey{}).(apiKeyAuth); ok {
+		return auth
+	}
+
+	ctx := request.Context()
+	counter := "api-key:" + web.GetClientIP(request)
+	limited := k.limiter != nil && k.rate.Enabled()
+	if limited {
+		// attempt is counted before check, so parallel guesses can't overrun the limit, and returned back on success
+		ok, err := ratelimit.Allow(ctx, k.limiter, counter, k.rate)
+		if err != nil {
+			slog.Error("failed check API key rate limit - request allowed", "error", err)
+		} else if !ok {
+			return apiKeyAuth{limited: true}
+		}
+	}
+
+	for _, list := range k.lists {
+		if key := list.Find(token); key != nil {
+			k.verified.Store(digest, key)
+			if limited {
+				if err := k.limiter.Undo(context.WithoutCancel(ctx), counter); err != nil {
+					slog.Error("failed undo API key rate limit hit", "error", err)
+				}
+			}
+			return apiKeyAuth{key: key}
+		}
+	}
+	return apiKeyAuth{invalid: true}
+}
+
+// authenticateRequest checks API key and adds credentials of service identity to the request for matched key.
+// Bearer tokens which are not API keys are ignored for requests authorized interactively (ex: by forward auth).
+func (k *Keys) authenticateRequest(request *web.Request) apiKeyAuth {
+	auth := k.authenticate(request.Request())
+	switch {
+	case auth.key != nil:
+		request.WithCredentials(auth.key.Credentials())
+	case request.Credentials() != nil:
+		auth = apiKeyAuth{}
+	}
+	return auth
+}
+
+func requiredScope(method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return schema.ScopeRead
+	}
+	return schema.ScopeSubmit
+}
+
+// apiKeyStatus is response code for rejected API key.
+func apiKeyStatus(auth apiKeyAuth) int {
+	switch {
+	case auth.limited:
+		return http.StatusTooManyRequests
+	case auth.invalid:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusForbidden
+	}
+}
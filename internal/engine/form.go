@@ -2,17 +2,31 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/reddec/web-form/internal/audit"
+	"github.com/reddec/web-form/internal/codes"
+	"github.com/reddec/web-form/internal/hooks"
+	"github.com/reddec/web-form/internal/i18n"
+	"github.com/reddec/web-form/internal/limits"
+	"github.com/reddec/web-form/internal/metrics"
 	"github.com/reddec/web-form/internal/notifications"
 	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/signed"
+	"github.com/reddec/web-form/internal/tracing"
 	"github.com/reddec/web-form/internal/utils"
 	"github.com/reddec/web-form/internal/web"
+
+	"github.com/oklog/ulid/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -33,6 +47,22 @@ type AMQPFactory interface {
 	Create(definition schema.AMQP) notifications.Notification
 }
 
+type HooksFactory interface {
+	Create(hook schema.Hook) hooks.Hook
+}
+
+// CodesStore keeps issued access codes.
+type CodesStore interface {
+	Get(ctx context.Context, form string, code string) (*codes.Code, error)
+	Consume(ctx context.Context, form string, code string) error
+	Refund(ctx context.Context, form string, code string) error
+}
+
+// LinkVerifier checks signed prefill links.
+type LinkVerifier interface {
+	Verify(form string, query url.Values, now time.Time) (url.Values, error)
+}
+
 type FormConfig struct {
 	Definition      schema.Form        // schema definition
 	ViewForm        *template.Template // template to show main form
@@ -40,17 +70,40 @@ type FormConfig struct {
 	ViewFail        *template.Template // template to show result (fail) after submit
 	ViewCode        *template.Template // template to show code access
 	ViewForbidden   *template.Template // template to show access denied
+	ViewLimited     *template.Template // template to show rate limit exceeded
+	ViewUpcoming    *template.Template // template to show not yet open form
+	ViewClosed      *template.Template // template to show closed form
 	Storage         Storage            // where to store data
 	WebhooksFactory WebhooksFactory
 	AMQPFactory     AMQPFactory
+	HooksFactory    HooksFactory
+	Audit           audit.Sink     // where to record access events
+	AuditKey        []byte         // key to fingerprint access codes in audit events, random if not set
+	Limits          limits.Store   // submission counters
+	Codes           CodesStore     // issued access codes, required if form accepts issued codes
+	Links           LinkVerifier   // signed prefill links, optional
+	APIKeys         schema.APIKeys // global API keys, in addition to keys from definition
+	RateLimit       RateLimit
 	XSRF            bool // check XSRF token. Disable if form is exposed as API.
 	Captcha         []web.Captcha
+	SpamChecks      []web.SpamCheck
+	Locales         *i18n.Bundle // message catalogs for UI, built-in by default
 }
 
 func NewForm(config FormConfig, options ...FormOption) http.HandlerFunc {
 	for _, opt := range options {
 		opt(&config)
 	}
+	if config.Audit == nil {
+		config.Audit = audit.Nop{}
+	}
+	config.Audit = audit.HideCodes(config.Audit, config.AuditKey)
+	if config.Limits == nil {
+		config.Limits = limits.NewMemory()
+	}
+	if config.Locales == nil {
+		config.Locales = i18n.Default()
+	}
 
 	var destinations []notifications.Notification
 
@@ -66,15 +119,30 @@ func NewForm(config FormConfig, options ...FormOption) http.HandlerFunc {
 		}
 	}
 
+	var beforeStore hooks.Hook
+	if config.HooksFactory != nil && config.Definition.Hooks.BeforeStore != nil {
+		beforeStore = config.HooksFactory.Create(*config.Definition.Hooks.BeforeStore)
+	}
+
+	rates := &rateGuard{RateLimit: &config.RateLimit, audit: config.Audit, view: config.ViewLimited}
+	keys := NewKeys(config.RateLimit.Limiter, config.RateLimit.APIKeys, config.Definition.APIKeys, config.APIKeys)
+	locales := config.Locales.With(config.Definition.Languages()...) // form may be translated to languages without UI catalog
+
 	return func(writer http.ResponseWriter, request *http.Request) {
 		defer request.Body.Close()
 
+		locale := localizer(locales, config.Definition.Locale, request)
+		localized := config
+		localized.Definition = config.Definition.Localize(locale.Language())
 		f := &formRequest{
-			FormConfig:   &config,
+			FormConfig:   &localized,
 			destinations: destinations,
+			beforeStore:  beforeStore,
+			rates:        rates,
+			keys:         keys,
 		}
 
-		r := web.NewRequest(writer, request).WithCaptcha(config.Captcha...).Set("Form", &f.Definition)
+		r := web.NewRequest(writer, request).WithLocalizer(locale).WithCaptcha(config.Captcha...).WithSpamChecks(config.SpamChecks...).Set("Form", &f.Definition).Set("Static", "../static")
 		f.Serve(r)
 	}
 }
@@ -82,35 +150,97 @@ func NewForm(config FormConfig, options ...FormOption) http.HandlerFunc {
 type formRequest struct {
 	*FormConfig
 	destinations []notifications.Notification
+	beforeStore  hooks.Hook
+	rates        *rateGuard
+	issued       *codes.Code // issued access code used for the request
+	signed       url.Values  // verified values from signed link
+	keys         *Keys
+	auth         apiKeyAuth // API key authentication (machine clients)
 }
 
 //nolint:cyclop
 func (fr *formRequest) Serve(request *web.Request) {
-	// check XSRF tokens (POST only)
-	if fr.XSRF && !request.VerifyXSRF() {
-		request.Error("XSRF validation failed")
+	// check global rate limit
+	if !fr.rates.allow(request, fr.Definition.Name, scopeGlobal, fr.RateLimit.Global) {
+		return
+	}
+
+	// check API key (if any) - after global rate limit, since keys are expensive to verify
+	fr.auth = fr.keys.authenticateRequest(request)
+	if fr.auth.limited {
+		fr.rates.reject(request, fr.Definition.Name, scopeAPIKey, fr.RateLimit.APIKeys)
+		return
+	}
+	if !fr.auth.allowed(request.Request().Method) {
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonAPIKey})
+		request.Header().Set("WWW-Authenticate", "Bearer")
+		request.Error(request.T("error.api-key"))
+		request.Render(apiKeyStatus(fr.auth), fr.ViewForbidden)
+		return
+	}
+
+	// check schedule
+	availability := fr.Definition.Availability(time.Now())
+	request.Set("Availability", availability)
+	switch availability.Status {
+	case schema.StatusUpcoming:
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonSchedule})
+		request.Render(http.StatusForbidden, fr.ViewUpcoming)
+		return
+	case schema.StatusClosed:
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonSchedule})
+		request.Render(http.StatusGone, fr.ViewClosed)
+		return
+	}
+
+	// check XSRF tokens (POST only, not for API keys)
+	if fr.XSRF && fr.auth.key == nil && !request.VerifyXSRF() {
+		metrics.XSRFFailures.WithLabelValues(fr.Definition.Name).Inc()
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonXSRF})
+		request.Error(request.T("error.xsrf"))
 		request.Render(http.StatusForbidden, fr.ViewForbidden)
 		return
 	}
 
 	// check credentials access (OIDC)
-	if !fr.Definition.IsAllowed(request.Credentials()) {
+	if !fr.Definition.Allows(newPolicyContext(request)) {
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonPolicy})
 		request.Render(http.StatusForbidden, fr.ViewForbidden)
 		return
 	}
 
+	// check signed prefill link (if any)
+	if !fr.verifyLink(request) {
+		return
+	}
+
 	// for code access forms, all interactions should be done via POST
 	if fr.Definition.HasCodeAccess() && request.Request().Method != http.MethodPost {
 		request.Render(http.StatusUnauthorized, fr.ViewCode)
 		return
 	}
 
+	// check per-form rate limit (POST only)
+	if request.Request().Method == http.MethodPost && !fr.rates.allow(request, fr.Definition.Name, scopeForm, fr.Definition.RateLimit) {
+		return
+	}
+
+	// protect access codes from brute-force: attempt is counted before check (so parallel guesses can't overrun
+	// the limit) and returned back if code is valid
+	if fr.Definition.HasCodeAccess() && !fr.rates.allow(request, fr.Definition.Name, scopeCode, fr.RateLimit.Codes) {
+		return
+	}
+
 	// check code access
-	if !validateCode(&fr.Definition, request) {
-		request.Error("invalid code")
+	if !fr.validateCode(request) {
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonCode})
+		request.Error(request.T("error.code"))
 		request.Render(http.StatusUnauthorized, fr.ViewCode)
 		return
 	}
+	if fr.Definition.HasCodeAccess() {
+		fr.rates.undo(request, fr.Definition.Name, scopeCode, fr.RateLimit.Codes)
+	}
 
 	// pre-render default values
 	if err := fr.preRender(request); err != nil {
@@ -119,21 +249,46 @@ func (fr *formRequest) Serve(request *web.Request) {
 
 	// if it's fresh start - show page without processing data
 	if request.Pop(freshField) == "true" || request.Request().Method == http.MethodGet {
+		metrics.FormViews.WithLabelValues(fr.Definition.Name).Inc()
+		fr.audit(request, audit.Event{Action: audit.ActionView})
 		request.Render(http.StatusOK, fr.ViewForm)
 		return
 	}
 
-	// check captcha (form post only)
-	if !request.VerifyCaptcha() {
-		request.Error("invalid captcha")
+	// check captcha (form post only, not for API keys)
+	if fr.auth.key == nil && !request.VerifyCaptcha() {
+		metrics.CaptchaFailures.WithLabelValues(fr.Definition.Name).Inc()
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonCaptcha})
+		request.Error(request.T("error.captcha"))
 		request.Render(http.StatusBadRequest, fr.ViewForm)
 		return
 	}
 
+	// check spam (form post only, not for API keys)
+	if fr.auth.key == nil {
+		if check, spam := request.DetectSpam(); spam {
+			fr.rejectSpam(request, check)
+			return
+		}
+	}
+
 	// it's not fresh start or get - submit the form
 	fr.submitForm(request)
 }
 
+// rejectSpam silently drops submission: client sees success page, so bots can't tune the submissions.
+func (fr *formRequest) rejectSpam(request *web.Request, check string) {
+	metrics.SpamDetected.WithLabelValues(fr.Definition.Name, check).Inc()
+	fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonSpam})
+	request.Logger().Info("submission rejected as spam", "check", check)
+
+	request.Push(freshField, "true")
+	request.Set("Result", &schema.ResultContext{
+		Form:       &fr.Definition,
+		Submission: ulid.Make().String(),
+	}).Render(http.StatusOK, fr.ViewSuccess)
+}
+
 func (fr *formRequest) submitForm(request *web.Request) {
 	tz := request.Session()[tzField]
 
@@ -150,24 +305,58 @@ func (fr *formRequest) submitForm(request *web.Request) {
 
 	_ = request.Request().FormValue("") // parse form using Go defaults
 
-	values, fieldErrors := schema.ParseForm(&fr.Definition, tzLocation, newRequestContext(request))
+	_, parseSpan := tracing.Start(request.Context(), "schema.ParseForm")
+	values, fieldErrors := schema.ParseForm(&fr.Definition, tzLocation, fr.requestContext(request))
+	parseSpan.SetAttributes(attribute.Int("field_errors", len(fieldErrors)))
+	parseSpan.End()
 
 	// save flash messages with name related to field name
 	for _, fieldError := range fieldErrors {
-		request.Flash(fieldError.Name, fieldError.Error, web.FlashError)
+		request.Flash(fieldError.Name, localizeError(request, fieldError.Error), web.FlashError)
 	}
 
 	if len(fieldErrors) > 0 {
+		metrics.Submissions.WithLabelValues(fr.Definition.Name, metrics.ResultValidationFailed).Inc()
+		fr.audit(request, audit.Event{Action: audit.ActionInvalid})
 		request.Logger().Info("form validation failed", toLogErrors(fieldErrors)...)
 		request.Render(http.StatusUnprocessableEntity, fr.ViewForm)
 		return
 	}
 
+	// external validation and enrichment
+	if !fr.callBeforeStore(request, values, tzLocation) {
+		return
+	}
+
+	// use issued access code
+	if !fr.consumeCode(request) {
+		return
+	}
+
+	// check and reserve submission limits
+	release, ok := fr.acquireLimits(request)
+	if !ok {
+		fr.refundCode(request)
+		return
+	}
+
 	// bellow we will show success or failed page
 	request.Push(freshField, "true")
-	result, storeErr := fr.Storage.Store(request.Context(), fr.Definition.Table, values)
+	submission := ulid.Make().String()
+	started := time.Now()
+	storeCtx, storeSpan := tracing.Start(request.Context(), "Storage.Store", trace.WithAttributes(attribute.String("table", fr.Definition.Table)))
+	result, storeErr := fr.Storage.Store(storeCtx, fr.Definition.Table, values)
+	tracing.Fail(storeSpan, storeErr)
+	storeSpan.End()
+	metrics.StoreDuration.WithLabelValues(fr.Definition.Name).Observe(time.Since(started).Seconds())
 	if storeErr != nil {
-		request.Error("failed to store data")
+		metrics.Submissions.WithLabelValues(fr.Definition.Name, metrics.ResultStoreFailed).Inc()
+		fr.audit(request, audit.Event{Action: audit.ActionFailed, Reason: audit.ReasonStorage})
+		if err := release(context.WithoutCancel(request.Context())); err != nil {
+			request.Logger().Error("failed release submission limits", "error", err)
+		}
+		fr.refundCode(request)
+		request.Error(request.T("error.store"))
 		request.Set("Result", &schema.ResultContext{
 			Form:   &fr.Definition,
 			Result: result,
@@ -176,16 +365,164 @@ func (fr *formRequest) submitForm(request *web.Request) {
 		request.Render(http.StatusInternalServerError, fr.ViewFail)
 		return
 	}
+	metrics.Submissions.WithLabelValues(fr.Definition.Name, metrics.ResultSuccess).Inc()
+	fr.audit(request, audit.Event{Action: audit.ActionSubmit, Submission: submission})
+	request.Logger().Info("submission stored", "submission", submission)
 
 	request.Set("Result", &schema.ResultContext{
-		Form:   &fr.Definition,
-		Result: result,
+		Form:       &fr.Definition,
+		Result:     result,
+		Submission: submission,
 	}).Render(http.StatusOK, fr.ViewSuccess)
 
 	fr.sendNotifications(request, schema.NotifyContext{
-		Form:   &fr.Definition,
-		Result: result,
+		Form:       &fr.Definition,
+		Result:     result,
+		Submission: submission,
+	})
+}
+
+// callBeforeStore executes synchronous hook (if defined) and returns true if submission can be stored.
+// Values may be modified by the hook, modified values are validated as user input.
+func (fr *formRequest) callBeforeStore(request *web.Request, values map[string]any, tzLocation *time.Location) bool {
+	if fr.beforeStore == nil {
+		return true
+	}
+	ctx, span := tracing.Start(request.Context(), "hooks.BeforeStore")
+	result, err := fr.beforeStore.Call(ctx, hooks.Event{
+		Form:   fr.Definition.Name,
+		User:   request.Credentials().GetUser(),
+		Lang:   request.Lang(),
+		Values: values,
 	})
+	tracing.Fail(span, err)
+	span.End()
+	if err != nil {
+		if fr.Definition.Hooks.BeforeStore.OnError.Allow() {
+			request.Logger().Warn("before-store hook failed - submission allowed by policy", "error", err)
+			return true
+		}
+		metrics.Submissions.WithLabelValues(fr.Definition.Name, metrics.ResultHookFailed).Inc()
+		fr.audit(request, audit.Event{Action: audit.ActionFailed, Reason: audit.ReasonHook})
+		request.Logger().Error("before-store hook failed - submission blocked by policy", "error", err)
+		request.Error(request.T("error.hook"))
+		request.Render(http.StatusBadGateway, fr.ViewForm)
+		return false
+	}
+
+	if fieldErrors := result.FieldErrors(); len(fieldErrors) > 0 {
+		fr.rejectByHook(request, fieldErrors)
+		request.Logger().Info("before-store hook rejected submission", toLogErrors(fieldErrors)...)
+		return false
+	}
+
+	if fieldErrors := result.Apply(values, fr.Definition.Fields, tzLocation, fr.requestContext(request)); len(fieldErrors) > 0 {
+		fr.rejectByHook(request, fieldErrors)
+		request.Logger().Warn("before-store hook returned invalid values", toLogErrors(fieldErrors)...)
+		return false
+	}
+	return true
+}
+
+func (fr *formRequest) rejectByHook(request *web.Request, fieldErrors []schema.FieldError) {
+	for _, fieldError := range fieldErrors {
+		request.Flash(fieldError.Name, localizeError(request, fieldError.Error), web.FlashError)
+	}
+	metrics.Submissions.WithLabelValues(fr.Definition.Name, metrics.ResultValidationFailed).Inc()
+	fr.audit(request, audit.Event{Action: audit.ActionInvalid, Reason: audit.ReasonHook})
+	request.Render(http.StatusUnprocessableEntity, fr.ViewForm)
+}
+
+// acquireLimits reserves one submission in all defined limits. Returns false if limit reached or limits can not be checked,
+// in that case response is already rendered. Returned function should be called if submission was not stored.
+func (fr *formRequest) acquireLimits(request *web.Request) (limits.Release, bool) {
+	keys := limits.Keys(&fr.Definition.Limits, request.Credentials().GetUser(), request.Session()[accessCodeField], web.GetClientIP(request.Request()))
+	if len(keys) == 0 {
+		return func(context.Context) error { return nil }, true
+	}
+
+	release, err := fr.Limits.Acquire(request.Context(), fr.Definition.Name, keys)
+	var exceeded *limits.ExceededError
+	if errors.As(err, &exceeded) {
+		metrics.Submissions.WithLabelValues(fr.Definition.Name, metrics.ResultLimitReached).Inc()
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonLimit})
+		request.Logger().Info("submission limit reached", "limit", exceeded.Key.Kind, "max", exceeded.Key.Max)
+		message, renderErr := fr.Definition.Limits.Message.String(&schema.LimitContext{
+			Form:  &fr.Definition,
+			Limit: exceeded.Key.Kind,
+			Max:   exceeded.Key.Max,
+		})
+		if renderErr != nil {
+			request.Logger().Error("failed render limit message", "error", renderErr)
+			message = request.T("error.limit")
+		}
+		request.Error(message)
+		request.Render(http.StatusTooManyRequests, fr.ViewForm)
+		return nil, false
+	}
+	if err != nil {
+		fr.audit(request, audit.Event{Action: audit.ActionFailed, Reason: audit.ReasonLimit})
+		request.Logger().Error("failed check submission limits", "error", err)
+		request.Error(request.T("error.limit-check"))
+		request.Render(http.StatusInternalServerError, fr.ViewForm)
+		return nil, false
+	}
+	return release, true
+}
+
+// consumeCode uses one submission of issued access code (if any). Returns false if code is no longer valid or
+// can not be checked, in that case response is already rendered.
+func (fr *formRequest) consumeCode(request *web.Request) bool {
+	if fr.issued == nil {
+		return true
+	}
+	err := fr.Codes.Consume(request.Context(), fr.Definition.Name, fr.issued.Code)
+	if errors.Is(err, codes.ErrInvalid) {
+		metrics.Submissions.WithLabelValues(fr.Definition.Name, metrics.ResultCodeRejected).Inc()
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonCode})
+		request.Logger().Info("issued access code expired or used up")
+		request.Pop(accessCodeField)
+		request.Error(request.T("error.code-used"))
+		request.Render(http.StatusUnauthorized, fr.ViewCode)
+		return false
+	}
+	if err != nil {
+		fr.audit(request, audit.Event{Action: audit.ActionFailed, Reason: audit.ReasonCode})
+		request.Logger().Error("failed consume issued access code", "error", err)
+		request.Error(request.T("error.code-check"))
+		request.Render(http.StatusInternalServerError, fr.ViewForm)
+		return false
+	}
+	return true
+}
+
+// refundCode returns consumed submission of issued access code (if any) back.
+func (fr *formRequest) refundCode(request *web.Request) {
+	if fr.issued == nil {
+		return
+	}
+	if err := fr.Codes.Refund(context.WithoutCancel(request.Context()), fr.Definition.Name, fr.issued.Code); err != nil {
+		request.Logger().Error("failed refund issued access code", "error", err)
+	}
+}
+
+func (fr *formRequest) audit(request *web.Request, event audit.Event) {
+	event.Form = fr.Definition.Name
+	recordAudit(fr.Audit, request, event)
+}
+
+// recordAudit fills event with details from request (user, code, IP) and records it. Errors are only logged.
+func recordAudit(sink audit.Sink, request *web.Request, event audit.Event) {
+	event.At = time.Now()
+	event.User = request.Credentials().GetUser()
+	if creds := request.Credentials(); creds != nil && creds.Service {
+		event.User = "api-key:" + creds.User
+	}
+	event.IP = web.GetClientIP(request.Request())
+	event.Code = requestCode(request)
+	if err := sink.Record(request.Context(), event); err != nil {
+		request.Logger().Error("failed record audit event", "action", event.Action, "error", err)
+	}
 }
 
 func (fr *formRequest) sendNotifications(request *web.Request, rc schema.NotifyContext) {
@@ -198,7 +535,10 @@ func (fr *formRequest) sendNotifications(request *web.Request, rc schema.NotifyC
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			ctx, span := tracing.Start(ctx, "notification.Dispatch")
+			defer span.End()
 			if err := notify.Dispatch(ctx, rc); err != nil {
+				tracing.Fail(span, err)
 				request.Logger().Error("failed dispatch notification", "error", err)
 			}
 		}()
@@ -209,7 +549,7 @@ func (fr *formRequest) sendNotifications(request *web.Request, rc schema.NotifyC
 
 func (fr *formRequest) preRender(request *web.Request) error {
 	var defaultValues = make(map[string]any, len(fr.Definition.Fields))
-	rct := newRequestContext(request)
+	rct := fr.requestContext(request)
 
 	description, err := fr.Definition.Description.String(rct)
 	if err != nil {
@@ -240,6 +580,23 @@ func (fr *formRequest) preRender(request *web.Request) error {
 	return nil
 }
 
+// requestCode returns access code from session or, for attempt to access by code, from form.
+func requestCode(request *web.Request) string {
+	if code := request.Session()[accessCodeField]; code != "" {
+		return code
+	}
+	return request.Request().PostFormValue(accessCodeField)
+}
+
+func newPolicyContext(request *web.Request) *schema.PolicyContext {
+	return &schema.PolicyContext{
+		Credentials: request.Credentials(),
+		IP:          web.GetClientIP(request.Request()),
+		Headers:     request.Request().Header,
+		Code:        requestCode(request),
+	}
+}
+
 func newRequestContext(request *web.Request) *schema.RequestContext {
 	return &schema.RequestContext{
 		Headers:     request.Request().Header,
@@ -250,8 +607,17 @@ func newRequestContext(request *web.Request) *schema.RequestContext {
 	}
 }
 
-func validateCode(form *schema.Form, request *web.Request) bool {
-	if !form.HasCodeAccess() {
+func (fr *formRequest) requestContext(request *web.Request) *schema.RequestContext {
+	rc := newRequestContext(request)
+	if fr.issued != nil {
+		rc.CodeValues = fr.issued.Values
+	}
+	rc.Signed = fr.signed
+	return rc
+}
+
+func (fr *formRequest) validateCode(request *web.Request) bool {
+	if !fr.Definition.HasCodeAccess() {
 		return true
 	}
 
@@ -268,7 +634,7 @@ func validateCode(form *schema.Form, request *web.Request) bool {
 		request.Push(freshField, "true")
 	}
 
-	if !form.Codes.Has(code) {
+	if !fr.Definition.Codes.Has(code) && !fr.checkIssued(request, code) {
 		return false
 	}
 	request.Session()[accessCodeField] = code // save code in order to re-use
@@ -276,6 +642,75 @@ func validateCode(form *schema.Form, request *web.Request) bool {
 	return true
 }
 
+// verifyLink checks signature of prefill link. Returns false if link is tampered or expired, in that case response is
+// already rendered. Links without signature are allowed, but signed values are empty.
+func (fr *formRequest) verifyLink(request *web.Request) bool {
+	if fr.Links == nil {
+		return true
+	}
+	values, err := fr.Links.Verify(fr.Definition.Name, request.Request().URL.Query(), time.Now())
+	if errors.Is(err, signed.ErrNotSigned) {
+		return true
+	}
+	if err != nil {
+		fr.audit(request, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonLink})
+		request.Logger().Info("signed link rejected", "error", err)
+		request.Error(request.T("error.link"))
+		request.Render(http.StatusForbidden, fr.ViewForbidden)
+		return false
+	}
+	fr.signed = values
+	return true
+}
+
+// checkIssued finds valid issued access code. Code is not consumed.
+func (fr *formRequest) checkIssued(request *web.Request, code string) bool {
+	if !fr.Definition.IssuedCodes || fr.Codes == nil || code == "" {
+		return false
+	}
+	issued, err := fr.Codes.Get(request.Context(), fr.Definition.Name, code)
+	if errors.Is(err, codes.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		request.Logger().Error("failed get issued access code", "error", err)
+		return false
+	}
+	if !issued.Valid(time.Now()) {
+		return false
+	}
+	fr.issued = issued
+	return true
+}
+
+// localizer selects UI language: fixed by form locale or by client preferences.
+func localizer(bundle *i18n.Bundle, locale string, request *http.Request) *i18n.Localizer {
+	if locale != "" {
+		return bundle.Localizer(locale)
+	}
+	return bundle.Localizer(request.Header.Get("Accept-Language"))
+}
+
+// localizeError translates known validation errors. Other errors are shown as-is.
+func localizeError(request *web.Request, err error) string {
+	var numErr *strconv.NumError
+	var timeErr *time.ParseError
+	switch {
+	case errors.Is(err, schema.ErrRequiredField):
+		return request.T("error.required")
+	case errors.Is(err, schema.ErrInvalidOption):
+		return request.T("error.option")
+	case errors.Is(err, schema.ErrWrongPattern):
+		return request.T("error.pattern")
+	case errors.Is(err, schema.ErrUnknownField):
+		return request.T("error.unknown-field")
+	case errors.Is(err, schema.ErrInvalidValue), errors.As(err, &numErr), errors.As(err, &timeErr):
+		return request.T("error.format")
+	default:
+		return err.Error()
+	}
+}
+
 func toLogErrors(fieldError []schema.FieldError) []any {
 	var ans = make([]any, 0, 2*len(fieldError))
 	for _, f := range fieldError {
@@ -2,22 +2,39 @@ package engine_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"html/template"
 	"maps"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/reddec/web-form/internal/antispam"
+	"github.com/reddec/web-form/internal/assets"
+	"github.com/reddec/web-form/internal/audit"
+	"github.com/reddec/web-form/internal/codes"
 	"github.com/reddec/web-form/internal/engine"
+	"github.com/reddec/web-form/internal/hooks"
+	"github.com/reddec/web-form/internal/metrics"
+	"github.com/reddec/web-form/internal/ratelimit"
 	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/signed"
+	"github.com/reddec/web-form/internal/storage"
 	"github.com/reddec/web-form/internal/utils"
+	"github.com/reddec/web-form/internal/web"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
 )
 
 const def = `
@@ -127,6 +144,8 @@ func TestBasic(t *testing.T) {
 			Value: "demo",
 		})
 		rec := httptest.NewRecorder()
+		submissions := metrics.Submissions.WithLabelValues("plain", metrics.ResultSuccess)
+		before := testutil.ToFloat64(submissions)
 
 		srv.ServeHTTP(rec, req)
 		require.Equal(t, http.StatusOK, rec.Code)
@@ -134,6 +153,7 @@ func TestBasic(t *testing.T) {
 		doc, err := goquery.NewDocumentFromReader(rec.Body)
 		require.NoError(t, err)
 		assertHasElement(t, doc, `a[href="plain"]`)
+		assert.Equal(t, 1.0, testutil.ToFloat64(submissions)-before)
 	})
 
 	t.Run("should show result (failed)", func(t *testing.T) {
@@ -160,6 +180,614 @@ func TestBasic(t *testing.T) {
 	})
 }
 
+func TestBeforeStoreHook(t *testing.T) {
+	const hookDef = `
+name: block
+table: block
+fields:
+  - name: name
+hooks:
+  before_store:
+    url: http://example.com
+---
+name: allow
+table: allow
+fields:
+  - name: name
+hooks:
+  before_store:
+    url: http://example.com
+    on_error: allow
+---
+name: enrich
+table: enrich
+fields:
+  - name: name
+  - name: seats
+    type: integer
+hooks:
+  before_store:
+    url: http://example.com
+`
+	storage := &mockStorage{}
+	forms, err := schema.FormsFromStream(strings.NewReader(hookDef))
+	require.NoError(t, err)
+
+	srv, err := engine.New(engine.Config{
+		Forms:   forms,
+		Storage: storage,
+		HooksFactory: hooksFunc(func(_ context.Context, event hooks.Event) (*hooks.Result, error) {
+			switch event.Form {
+			case "enrich":
+				assert.Equal(t, "en", event.Lang)
+				switch event.Values["name"] {
+				case "bad":
+					return &hooks.Result{Errors: map[string]string{"name": "bad name"}}, nil
+				case "unknown":
+					return &hooks.Result{Values: map[string]any{"name": "enriched", "id": json.Number("100")}}, nil
+				case "invalid":
+					return &hooks.Result{Values: map[string]any{"name": "enriched", "seats": "many"}}, nil
+				}
+				return &hooks.Result{Values: map[string]any{"name": "enriched", "seats": json.Number("3")}}, nil
+			default:
+				return nil, fmt.Errorf("simulated error")
+			}
+		}),
+	})
+	require.NoError(t, err)
+
+	t.Run("failed hook blocks submission", func(t *testing.T) {
+		rec := postForm(srv, "/forms/block", url.Values{"name": {"RedDec"}})
+		require.Equal(t, http.StatusBadGateway, rec.Code)
+		_, ok := storage.tables.Load("block")
+		require.False(t, ok)
+	})
+
+	t.Run("failed hook allows submission", func(t *testing.T) {
+		rec := postForm(srv, "/forms/allow", url.Values{"name": {"RedDec"}})
+		require.Equal(t, http.StatusOK, rec.Code)
+		row, ok := storage.getTable("allow").rows.Load(int64(1))
+		require.True(t, ok)
+		assert.Equal(t, "RedDec", row.(map[string]any)["name"])
+	})
+
+	t.Run("hook rejects submission", func(t *testing.T) {
+		rec := postForm(srv, "/forms/enrich", url.Values{"name": {"bad"}})
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "bad name")
+	})
+
+	t.Run("hook enriches submission", func(t *testing.T) {
+		rec := postForm(srv, "/forms/enrich", url.Values{"name": {"RedDec"}})
+		require.Equal(t, http.StatusOK, rec.Code)
+		row, ok := storage.getTable("enrich").rows.Load(int64(1))
+		require.True(t, ok)
+		assert.Equal(t, "enriched", row.(map[string]any)["name"])
+		assert.Equal(t, int64(3), row.(map[string]any)["seats"], "values are parsed by field type")
+	})
+
+	t.Run("hook values are validated", func(t *testing.T) {
+		rec := postForm(srv, "/forms/enrich", url.Values{"name": {"unknown"}})
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "unknown field")
+
+		rec = postForm(srv, "/forms/enrich", url.Values{"name": {"invalid"}})
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid value")
+		assert.NotContains(t, rec.Body.String(), "strconv")
+
+		_, ok := storage.getTable("enrich").rows.Load(int64(2))
+		assert.False(t, ok)
+	})
+}
+
+func TestAudit(t *testing.T) {
+	forms, err := schema.FormsFromStream(strings.NewReader(def))
+	require.NoError(t, err)
+
+	sink := &mockAudit{}
+	srv, err := engine.New(engine.Config{
+		Forms:    forms,
+		Storage:  &mockStorage{},
+		Listing:  true,
+		Audit:    sink,
+		AuditKey: []byte("audit-key"),
+	})
+	require.NoError(t, err)
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/forms/plain", nil))
+	postForm(srv, "/forms/plain", url.Values{"name": {"RedDec"}})
+	postForm(srv, "/forms/plain", url.Values{"name": {"RedDec"}, "year": {"2023"}})
+	postForm(srv, "/forms/code-access", url.Values{"accessCode": {"wrong"}})
+
+	events := sink.events
+	require.Len(t, events, 5)
+	assert.Equal(t, audit.ActionList, events[0].Action)
+	assert.Equal(t, audit.Event{Action: audit.ActionView, Form: "plain", IP: "192.0.2.1"}, withoutTime(events[1]))
+	assert.Equal(t, audit.ActionInvalid, events[2].Action)
+	assert.Equal(t, audit.ActionSubmit, events[3].Action)
+	assert.NotEmpty(t, events[3].Submission)
+	assert.Equal(t, audit.Event{Action: audit.ActionDenied, Form: "code-access", Code: audit.Fingerprint([]byte("audit-key"), "wrong"), IP: "192.0.2.1", Reason: audit.ReasonCode}, withoutTime(events[4]))
+	assert.NotContains(t, events[4].Code, "wrong")
+}
+
+func TestLimits(t *testing.T) {
+	const limitsDef = `
+name: seats
+table: seats
+fields:
+  - name: name
+limits:
+  total: 1
+  message: "{{.Form.Name}} is full ({{.Max}})"
+---
+name: crash
+table: crash
+fields:
+  - name: name
+limits:
+  per_ip: 1
+`
+	forms, err := schema.FormsFromStream(strings.NewReader(limitsDef))
+	require.NoError(t, err)
+
+	srv, err := engine.New(engine.Config{
+		Forms:   forms,
+		Storage: &mockStorage{failedTables: utils.NewSet("crash")},
+	})
+	require.NoError(t, err)
+
+	t.Run("total limit", func(t *testing.T) {
+		rec := postForm(srv, "/forms/seats", url.Values{"name": {"first"}})
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = postForm(srv, "/forms/seats", url.Values{"name": {"second"}})
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Contains(t, rec.Body.String(), "seats is full (1)")
+	})
+
+	t.Run("failed submissions are not counted", func(t *testing.T) {
+		rec := postForm(srv, "/forms/crash", url.Values{"name": {"first"}})
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+
+		rec = postForm(srv, "/forms/crash", url.Values{"name": {"second"}})
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	const rateDef = `
+name: plain
+table: plain
+fields:
+  - name: name
+rate_limit: 1/1m
+---
+name: code-access
+table: code
+fields:
+  - name: name
+codes:
+  - reddec
+`
+	forms, err := schema.FormsFromStream(strings.NewReader(rateDef))
+	require.NoError(t, err)
+
+	newServer := func(rateLimit engine.RateLimit) http.Handler {
+		rateLimit.Limiter = ratelimit.NewMemory()
+		srv, err := engine.New(engine.Config{
+			Forms:     forms,
+			Storage:   &mockStorage{},
+			Listing:   true,
+			RateLimit: rateLimit,
+		})
+		require.NoError(t, err)
+		return srv
+	}
+
+	t.Run("global", func(t *testing.T) {
+		srv := newServer(engine.RateLimit{Global: ratelimit.Rate{Limit: 2, Window: time.Minute}})
+		for _, path := range []string{"/", "/forms/plain"} {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/forms/code-access", nil))
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("per form", func(t *testing.T) {
+		srv := newServer(engine.RateLimit{})
+		rec := postForm(srv, "/forms/plain", url.Values{"name": {"first"}})
+		require.Equal(t, http.StatusOK, rec.Code)
+		rec = postForm(srv, "/forms/plain", url.Values{"name": {"second"}})
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	})
+
+	t.Run("access codes", func(t *testing.T) {
+		srv := newServer(engine.RateLimit{Codes: ratelimit.Rate{Limit: 2, Window: time.Minute}})
+		for i := 0; i < 2; i++ {
+			rec := postForm(srv, "/forms/code-access", url.Values{"accessCode": {"wrong"}})
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+		}
+		// even valid code is rejected after too many attempts
+		rec := postForm(srv, "/forms/code-access", url.Values{"accessCode": {"reddec"}})
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	})
+
+	t.Run("valid access codes are not counted", func(t *testing.T) {
+		srv := newServer(engine.RateLimit{Codes: ratelimit.Rate{Limit: 2, Window: time.Minute}})
+		for i := 0; i < 3; i++ {
+			rec := postForm(srv, "/forms/code-access", url.Values{"accessCode": {"reddec"}})
+			require.Equal(t, http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("parallel access code guesses", func(t *testing.T) {
+		srv := newServer(engine.RateLimit{Codes: ratelimit.Rate{Limit: 2, Window: time.Minute}})
+		var unauthorized atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if postForm(srv, "/forms/code-access", url.Values{"accessCode": {"wrong"}}).Code == http.StatusUnauthorized {
+					unauthorized.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int64(2), unauthorized.Load())
+	})
+}
+
+func TestSchedule(t *testing.T) {
+	const scheduleDef = `
+name: upcoming
+table: upcoming
+opens_at: 2100-01-01T00:00:00Z
+fields:
+  - name: name
+---
+name: closed
+table: closed
+closes_at: 2000-01-01T00:00:00Z
+fields:
+  - name: name
+---
+name: open
+table: open
+opens_at: 2000-01-01T00:00:00Z
+closes_at: 2100-01-01T00:00:00Z
+fields:
+  - name: name
+`
+	forms, err := schema.FormsFromStream(strings.NewReader(scheduleDef))
+	require.NoError(t, err)
+
+	srv, err := engine.New(engine.Config{
+		Forms:   forms,
+		Storage: &mockStorage{},
+		Listing: true,
+	})
+	require.NoError(t, err)
+
+	t.Run("upcoming", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/forms/upcoming", nil))
+		require.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "2100-01-01")
+
+		rec = postForm(srv, "/forms/upcoming", url.Values{"name": {"RedDec"}})
+		require.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		rec := postForm(srv, "/forms/closed", url.Values{"name": {"RedDec"}})
+		require.Equal(t, http.StatusGone, rec.Code)
+	})
+
+	t.Run("open", func(t *testing.T) {
+		rec := postForm(srv, "/forms/open", url.Values{"name": {"RedDec"}})
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("listing", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		doc, err := goquery.NewDocumentFromReader(rec.Body)
+		require.NoError(t, err)
+		assertHasElement(t, doc, `a[href="forms/open"]`)
+		assert.Zero(t, doc.Find(`a[href="forms/upcoming"]`).Length())
+		assert.Zero(t, doc.Find(`a[href="forms/closed"]`).Length())
+		assert.Contains(t, doc.Text(), "opens at 2100-01-01")
+	})
+}
+
+func TestIssuedCodes(t *testing.T) {
+	const codesDef = `
+name: invite
+table: invite
+issued_codes: true
+fields:
+  - name: guest
+    disabled: true
+    default: "{{.CodeValues.name}}"
+  - name: comment
+`
+	ctx := context.Background()
+	forms, err := schema.FormsFromStream(strings.NewReader(codesDef))
+	require.NoError(t, err)
+
+	_, err = engine.New(engine.Config{Forms: forms, Storage: &mockStorage{}})
+	require.ErrorIs(t, err, engine.ErrNoCodesStore)
+
+	db, err := storage.NewDB(ctx, "sqlite", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	store, err := codes.NewDB(ctx, db.DB())
+	require.NoError(t, err)
+
+	minted, err := store.Mint(ctx, "invite", codes.Mint{MaxUses: 1, Values: []map[string]string{{"name": "Alice"}}})
+	require.NoError(t, err)
+	require.Len(t, minted, 1)
+	code := minted[0].Code
+
+	result := &mockStorage{}
+	srv, err := engine.New(engine.Config{Forms: forms, Storage: result, Codes: store})
+	require.NoError(t, err)
+
+	rec := postForm(srv, "/forms/invite", url.Values{"accessCode": {"unknown"}})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = postForm(srv, "/forms/invite", url.Values{"accessCode": {code}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Alice")
+
+	// bound value can not be overwritten by user
+	rec = postForm(srv, "/forms/invite", url.Values{"__accessCode": {code}, "guest": {"Mallory"}, "comment": {"hello"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	row, ok := result.getTable("invite").rows.Load(int64(1))
+	require.True(t, ok)
+	assert.Equal(t, "Alice", row.(map[string]any)["guest"])
+
+	// code used up
+	rec = postForm(srv, "/forms/invite", url.Values{"__accessCode": {code}, "comment": {"again"}})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	issued, err := store.Get(ctx, "invite", code)
+	require.NoError(t, err)
+	assert.Equal(t, 1, issued.Uses)
+}
+
+func TestSignedLinks(t *testing.T) {
+	const linksDef = `
+name: invite
+table: invite
+fields:
+  - name: email
+    disabled: true
+    default: '{{.Signed.Get "email"}}'
+`
+	forms, err := schema.FormsFromStream(strings.NewReader(linksDef))
+	require.NoError(t, err)
+
+	signer, err := signed.New([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+
+	result := &mockStorage{}
+	srv, err := engine.New(engine.Config{Forms: forms, Storage: result, Links: signer})
+	require.NoError(t, err)
+
+	query := signer.Sign("invite", url.Values{"email": {"alice@example.com"}}, time.Now().Add(time.Hour))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/forms/invite?"+query.Encode(), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "alice@example.com")
+
+	tampered, _ := url.ParseQuery(query.Encode())
+	tampered.Set("email", "mallory@example.com")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/forms/invite?"+tampered.Encode(), nil))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	expired := signer.Sign("invite", url.Values{"email": {"alice@example.com"}}, time.Now().Add(-time.Second))
+	rec = postForm(srv, "/forms/invite?"+expired.Encode(), url.Values{})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	// signed value can not be overwritten by user
+	rec = postForm(srv, "/forms/invite?"+query.Encode(), url.Values{"email": {"mallory@example.com"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	row, ok := result.getTable("invite").rows.Load(int64(1))
+	require.True(t, ok)
+	assert.Equal(t, "alice@example.com", row.(map[string]any)["email"])
+}
+
+func TestAPIKeys(t *testing.T) {
+	const keysDef = `
+name: orders
+table: orders
+policy: 'service && "robots" in groups'
+fields:
+  - name: name
+    required: true
+api_keys:
+  - name: crm
+    key: crm-secret
+    groups: [robots]
+`
+	forms, err := schema.FormsFromStream(strings.NewReader(keysDef))
+	require.NoError(t, err)
+
+	sink := &mockAudit{}
+	result := &mockStorage{}
+	srv, err := engine.New(engine.Config{
+		Forms:   forms,
+		Storage: result,
+		Audit:   sink,
+		Listing: true,
+		APIKeys: schema.APIKeys{
+			{Name: "reader", Key: schema.PlainCode("reader-secret"), Scopes: []string{schema.ScopeRead}, Groups: []string{"robots"}},
+		},
+	}, engine.WithXSRF(true))
+	require.NoError(t, err)
+
+	call := func(method string, path string, token string, params url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// XSRF is not required for API keys
+	rec := call(http.MethodPost, "/forms/orders", "crm-secret", url.Values{"name": {"robot"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	_, ok := result.getTable("orders").rows.Load(int64(1))
+	require.True(t, ok)
+
+	rec = call(http.MethodPost, "/forms/orders", "wrong", url.Values{"name": {"robot"}})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// read-only key
+	rec = call(http.MethodPost, "/forms/orders", "reader-secret", url.Values{"name": {"robot"}})
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	rec = call(http.MethodGet, "/forms/orders", "reader-secret", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = call(http.MethodGet, "/", "reader-secret", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// form keys are not valid for listing
+	rec = call(http.MethodGet, "/", "crm-secret", nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// browser users still need XSRF
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/forms/orders", strings.NewReader("")))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	events := sink.events
+	require.NotEmpty(t, events)
+	assert.Equal(t, "api-key:crm", events[0].User)
+	assert.Equal(t, audit.ActionSubmit, events[0].Action)
+	assert.Equal(t, audit.ReasonAPIKey, events[1].Reason)
+}
+
+func TestAPIKeys_rateLimit(t *testing.T) {
+	forms, err := schema.FormsFromStream(strings.NewReader(`
+name: orders
+table: orders
+fields:
+  - name: name
+api_keys:
+  - name: crm
+    key: crm-secret
+    scopes: [read]
+`))
+	require.NoError(t, err)
+
+	srv, err := engine.New(engine.Config{
+		Forms:   forms,
+		Storage: &mockStorage{},
+		RateLimit: engine.RateLimit{
+			Limiter: ratelimit.NewMemory(),
+			APIKeys: ratelimit.Rate{Limit: 2, Window: time.Minute},
+		},
+	})
+	require.NoError(t, err)
+
+	call := func(token string, creds *schema.Credentials) int {
+		req := httptest.NewRequest(http.MethodGet, "/forms/orders", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if creds != nil {
+			req = req.WithContext(schema.WithCredentials(req.Context(), creds))
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// valid keys are not counted
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusOK, call("crm-secret", nil))
+	}
+	require.Equal(t, http.StatusUnauthorized, call("wrong", nil))
+	require.Equal(t, http.StatusUnauthorized, call("wrong", nil))
+	require.Equal(t, http.StatusTooManyRequests, call("wrong", nil))
+	// verified key is cached and still accepted
+	require.Equal(t, http.StatusOK, call("crm-secret", nil))
+	// foreign tokens (ex: from authenticating proxy) are ignored for interactively authorized users
+	require.Equal(t, http.StatusOK, call("id-token", &schema.Credentials{User: "alice"}))
+}
+
+func TestKeys_Skip(t *testing.T) {
+	keys := engine.NewKeys(nil, ratelimit.Rate{}, schema.APIKeys{{Name: "crm", Key: schema.PlainCode("crm-secret")}})
+	var interactive bool
+	handler := keys.Skip(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			interactive = true
+			next.ServeHTTP(writer, request)
+		})
+	})(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+
+	for token, expected := range map[string]bool{"": true, "crm-secret": false, "id-token": true} {
+		interactive = false
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, expected, interactive, token)
+	}
+}
+
+type mockAudit struct {
+	lock   sync.Mutex
+	events []audit.Event
+}
+
+func (ma *mockAudit) Record(_ context.Context, event audit.Event) error {
+	ma.lock.Lock()
+	defer ma.lock.Unlock()
+	ma.events = append(ma.events, event)
+	return nil
+}
+
+func withoutTime(event audit.Event) audit.Event {
+	event.At = time.Time{}
+	return event
+}
+
+type hooksFunc func(ctx context.Context, event hooks.Event) (*hooks.Result, error)
+
+func (hf hooksFunc) Create(schema.Hook) hooks.Hook {
+	return hf
+}
+
+func (hf hooksFunc) Call(ctx context.Context, event hooks.Event) (*hooks.Result, error) {
+	return hf(ctx, event)
+}
+
+func postForm(srv http.Handler, path string, params url.Values) *httptest.ResponseRecorder {
+	params.Set("_xsrf", "demo")
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{
+		Name:  "_xsrf",
+		Value: "demo",
+	})
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
 func assertHasElement(t *testing.T, doc *goquery.Document, selector string) {
 	assert.True(t, doc.Find(selector).Length() > 0, "exists element: %q", selector)
 }
@@ -194,3 +822,323 @@ func (mt *mockTable) Add(row map[string]any) int64 {
 	mt.rows.Store(id, row)
 	return id
 }
+
+func TestFormCaptcha(t *testing.T) {
+	const captchaDef = `
+name: default
+table: default
+fields:
+  - name: name
+---
+name: disabled
+table: disabled
+captcha: none
+fields:
+  - name: name
+---
+name: selected
+table: selected
+captcha: beta
+fields:
+  - name: name
+`
+	forms, err := schema.FormsFromStream(strings.NewReader(captchaDef))
+	require.NoError(t, err)
+
+	captchas := map[string]web.Captcha{"alpha": mockCaptcha("alpha"), "beta": mockCaptcha("beta")}
+	srv, err := engine.New(engine.Config{Forms: forms, Storage: &mockStorage{}, Captcha: captchas})
+	require.NoError(t, err)
+
+	embedded := func(form string) string {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/forms/"+form, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		doc, err := goquery.NewDocumentFromReader(rec.Body)
+		require.NoError(t, err)
+		return strings.Join(doc.Find(".mock-captcha").Map(func(_ int, s *goquery.Selection) string {
+			return s.Text()
+		}), ",")
+	}
+
+	assert.Equal(t, "alpha,beta", embedded("default"))
+	assert.Equal(t, "", embedded("disabled"))
+	assert.Equal(t, "beta", embedded("selected"))
+
+	// captcha is validated
+	rec := postForm(srv, "/forms/selected", url.Values{"name": {"reddec"}})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = postForm(srv, "/forms/selected", url.Values{"name": {"reddec"}, "mock-captcha": {"beta"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	_, err = engine.New(engine.Config{Forms: forms, Storage: &mockStorage{}, Captcha: map[string]web.Captcha{"alpha": mockCaptcha("alpha")}})
+	require.ErrorIs(t, err, engine.ErrUnknownCaptcha)
+}
+
+type mockCaptcha string
+
+func (mc mockCaptcha) Embed() template.HTML {
+	return template.HTML(`<div class="mock-captcha">` + template.HTMLEscapeString(string(mc)) + `</div>`) //nolint:gosec
+}
+
+func (mc mockCaptcha) Validate(form *http.Request) bool {
+	return form.Form.Get("mock-captcha") == string(mc)
+}
+
+func TestSpamChecks(t *testing.T) {
+	forms, err := schema.FormsFromStream(strings.NewReader(def))
+	require.NoError(t, err)
+
+	timing, err := antispam.NewTiming([]byte("0123456789abcdef"), time.Second, time.Hour)
+	require.NoError(t, err)
+
+	result := &mockStorage{}
+	srv, err := engine.New(engine.Config{
+		Forms:      forms,
+		Storage:    result,
+		SpamChecks: []web.SpamCheck{&antispam.Honeypot{Field: "website"}, timing},
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/forms/plain", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	doc, err := goquery.NewDocumentFromReader(rec.Body)
+	require.NoError(t, err)
+	assertHasElement(t, doc, `form input[name="website"]`)
+	assertHasElement(t, doc, `form input[name="_rendered"]`)
+
+	valid := func() url.Values {
+		return url.Values{"name": {"reddec"}, "year": {"2023"}, "_rendered": {timing.Stamp(time.Now().Add(-time.Minute))}}
+	}
+	rows := func() int64 {
+		return result.getTable("plain").id.Load()
+	}
+	spam := func(check string) float64 {
+		return testutil.ToFloat64(metrics.SpamDetected.WithLabelValues("plain", check))
+	}
+	honeypotBefore, timingBefore := spam(antispam.NameHoneypot), spam(antispam.NameTiming)
+
+	// spam is silently dropped: success page, but nothing stored
+	honeypot := valid()
+	honeypot.Set("website", "https://spam.example.com")
+	rec = postForm(srv, "/forms/plain", honeypot)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(0), rows())
+	assert.Equal(t, float64(1), spam(antispam.NameHoneypot)-honeypotBefore)
+
+	for _, stamp := range []string{"", "123.bad", timing.Stamp(time.Now()), timing.Stamp(time.Now().Add(-2 * time.Hour))} {
+		fast := valid()
+		fast.Set("_rendered", stamp)
+		rec = postForm(srv, "/forms/plain", fast)
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, int64(0), rows())
+	assert.Equal(t, float64(4), spam(antispam.NameTiming)-timingBefore)
+
+	rec = postForm(srv, "/forms/plain", valid())
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(1), rows())
+}
+
+func TestSpamChecks_fieldConflict(t *testing.T) {
+	forms, err := schema.FormsFromStream(strings.NewReader(def))
+	require.NoError(t, err)
+
+	for _, name := range []string{"name", "accessCode", "_xsrf", "__fresh", antispam.FieldRendered} {
+		t.Run(name, func(t *testing.T) {
+			timing, err := antispam.NewTiming(nil, time.Second, 0)
+			require.NoError(t, err)
+			_, err = engine.New(engine.Config{
+				Forms:      forms,
+				Storage:    &mockStorage{},
+				SpamChecks: []web.SpamCheck{timing, &antispam.Honeypot{Field: name}},
+			})
+			require.ErrorIs(t, err, engine.ErrFieldConflict)
+		})
+	}
+}
+
+func TestLocalization(t *testing.T) {
+	forms, err := schema.FormsFromStream(strings.NewReader(`
+name: survey
+table: survey
+title: Survey
+fields:
+  - name: color
+    label: Color
+    required: true
+    options:
+      - label: Red
+      - label: Green
+        value: green
+    i18n:
+      de:
+        label: Farbe
+        options:
+          Red: Rot
+          green: Grün
+  - name: year
+    type: integer
+i18n:
+  de:
+    title: Umfrage
+  fr:
+    title: Sondage
+---
+name: fixed
+table: fixed
+locale: ru
+fields:
+  - name: name
+    required: true
+`))
+	require.NoError(t, err)
+
+	result := &mockStorage{}
+	srv, err := engine.New(engine.Config{Forms: forms, Storage: result, Listing: true})
+	require.NoError(t, err)
+
+	render := func(method, path, lang string, params url.Values) (int, *goquery.Document) {
+		params.Set("_xsrf", "demo")
+		req := httptest.NewRequest(method, path, strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept-Language", lang)
+		req.AddCookie(&http.Cookie{Name: "_xsrf", Value: "demo"})
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		doc, err := goquery.NewDocumentFromReader(rec.Body)
+		require.NoError(t, err)
+		return rec.Code, doc
+	}
+
+	t.Run("by Accept-Language", func(t *testing.T) {
+		code, doc := render(http.MethodGet, "/forms/survey", "de-DE,de;q=0.9,en;q=0.8", url.Values{})
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "de", doc.Find("html").AttrOr("lang", ""))
+		assert.Equal(t, "Umfrage", doc.Find("title").Text())
+		assert.Contains(t, doc.Find("form label.label").Text(), "Farbe")
+		assert.Equal(t, "absenden", doc.Find(`form button[type="submit"]`).Text())
+		// stored values are not changed by translation
+		assert.Equal(t, "Red", doc.Find(`option:contains("Rot")`).AttrOr("value", ""))
+		assert.Equal(t, "green", doc.Find(`option:contains("Grün")`).AttrOr("value", ""))
+	})
+
+	t.Run("form language without UI catalog", func(t *testing.T) {
+		code, doc := render(http.MethodGet, "/forms/survey", "fr-FR,fr;q=0.9,de;q=0.8", url.Values{})
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "fr", doc.Find("html").AttrOr("lang", ""))
+		assert.Equal(t, "Sondage", doc.Find("title").Text())
+		assert.Equal(t, "send", doc.Find(`form button[type="submit"]`).Text(), "UI messages from default language")
+
+		code, doc = render(http.MethodGet, "/", "fr", url.Values{})
+		require.Equal(t, http.StatusOK, code)
+		assert.Contains(t, doc.Text(), "Sondage")
+	})
+
+	t.Run("unsupported language", func(t *testing.T) {
+		code, doc := render(http.MethodGet, "/forms/survey", "xx", url.Values{})
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "en", doc.Find("html").AttrOr("lang", ""))
+		assert.Equal(t, "Survey", doc.Find("title").Text())
+		assert.Equal(t, "send", doc.Find(`form button[type="submit"]`).Text())
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		code, doc := render(http.MethodPost, "/forms/survey", "de", url.Values{"color": {"blue"}, "year": {"abc"}})
+		require.Equal(t, http.StatusUnprocessableEntity, code)
+		errs := doc.Find("p.help.is-danger").Text()
+		assert.Contains(t, errs, "ausgewählte Option ist nicht erlaubt")
+		assert.Contains(t, errs, "ungültiger Wert")
+
+		code, _ = render(http.MethodPost, "/forms/survey", "de", url.Values{"color": {"Red"}, "year": {"2023"}})
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(1), result.getTable("survey").id.Load())
+	})
+
+	t.Run("fixed locale", func(t *testing.T) {
+		code, doc := render(http.MethodPost, "/forms/fixed", "de", url.Values{})
+		require.Equal(t, http.StatusUnprocessableEntity, code)
+		assert.Equal(t, "ru", doc.Find("html").AttrOr("lang", ""))
+		assert.Contains(t, doc.Find("p.help.is-danger").Text(), "обязательное поле не заполнено")
+	})
+}
+
+func TestViews(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	write("access.gohtml", `{{define "main"}}<p id="custom-access">brand access</p><form method="post">{{$.EmbedXSRF}}{{$.EmbedSession}}{{$.EmbedCaptcha}}</form>{{end}}`)
+	write("pizza.gohtml", `{{define "main"}}<p id="pizza">{{$.State.Form.Title}}</p><form method="post">{{.EmbedXSRF}}{{.EmbedSession}}{{.EmbedSpamChecks}}{{with .}}{{.EmbedCaptcha}}{{end}}</form>{{end}}`)
+	write("broken.gohtml", `{{define "main"}}{{.Unclosed{{end}}`)
+	write("no-xsrf.gohtml", `{{define "main"}}<form method="post">{{$.EmbedSession}}{{$.EmbedSpamChecks}}{{$.EmbedCaptcha}}</form>{{end}}`)
+
+	views, err := assets.ViewsFrom(dir)
+	require.NoError(t, err)
+
+	forms, err := schema.FormsFromStream(strings.NewReader(def + `
+---
+name: pizza
+table: pizza
+title: Order pizza
+fields:
+  - name: name
+theme:
+  views:
+    form: pizza.gohtml
+  css: ".box { border-top: 4px solid #ff6600 }"
+  stylesheets:
+    - /assets/brand.css
+`))
+	require.NoError(t, err)
+
+	srv, err := engine.New(engine.Config{Forms: forms, Storage: &mockStorage{}, Views: views})
+	require.NoError(t, err)
+
+	get := func(path string) *goquery.Document {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		doc, err := goquery.NewDocumentFromReader(rec.Body)
+		require.NoError(t, err)
+		return doc
+	}
+
+	// global override with fallback to built-in views
+	doc := get("/forms/code-access")
+	assertHasElement(t, doc, "#custom-access")
+	doc = get("/forms/plain")
+	assertHasElement(t, doc, `form input[name="name"]`)
+	assert.Equal(t, 0, doc.Find("style").Length())
+
+	// per-form template and styles
+	doc = get("/forms/pizza")
+	assert.Equal(t, "Order pizza", doc.Find("#pizza").Text())
+	assert.Equal(t, ".box { border-top: 4px solid #ff6600 }", doc.Find("style").Text())
+	assertHasElement(t, doc, `link[href="/assets/brand.css"]`)
+
+	// invalid templates are detected on start
+	for _, theme := range []string{
+		"views: {unknown: pizza.gohtml}",
+		"views: {form: missing.gohtml}",
+		"views: {form: broken.gohtml}",
+		"views: {form: no-xsrf.gohtml}",
+		"layout: missing.gohtml",
+	} {
+		forms, err := schema.FormsFromStream(strings.NewReader("name: bad\ntheme: {" + theme + "}\n"))
+		require.NoError(t, err, theme)
+		_, err = engine.New(engine.Config{Forms: forms, Storage: &mockStorage{}, Views: views})
+		assert.Error(t, err, theme)
+	}
+	forms, err = schema.FormsFromStream(strings.NewReader("name: bad\ntheme: {views: {unknown: pizza.gohtml}}\n"))
+	require.NoError(t, err)
+	_, err = engine.New(engine.Config{Forms: forms, Storage: &mockStorage{}, Views: views})
+	assert.ErrorIs(t, err, engine.ErrUnknownView)
+	forms, err = schema.FormsFromStream(strings.NewReader("name: bad\ntheme: {views: {form: no-xsrf.gohtml}}\n"))
+	require.NoError(t, err)
+	_, err = engine.New(engine.Config{Forms: forms, Storage: &mockStorage{}, Views: views})
+	assert.ErrorIs(t, err, assets.ErrMissingField)
+
+	write("list.gohtml", `{{range .State.Definitions}}`)
+	_, err = engine.New(engine.Config{Forms: forms[:0], Storage: &mockStorage{}, Views: views})
+	assert.Error(t, err)
+}
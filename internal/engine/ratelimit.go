@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/reddec/web-form/internal/audit"
+	"github.com/reddec/web-form/internal/metrics"
+	"github.com/reddec/web-form/internal/ratelimit"
+	"github.com/reddec/web-form/internal/web"
+)
+
+// Rate limit scopes.
+const (
+	scopeGlobal = "global"
+	scopeForm   = "form"
+	scopeCode   = "code"
+	scopeAPIKey = "api_key"
+)
+
+// RateLimit configuration for forms and listing. Disabled if Limiter is nil.
+type RateLimit struct {
+	Limiter ratelimit.Limiter
+	Global  ratelimit.Rate // all requests per client
+	Codes   ratelimit.Rate // failed access code attempts per client and form
+	APIKeys ratelimit.Rate // failed API key attempts per client IP
+	ByUser  bool           // identify authorized clients by user name instead of IP
+}
+
+// rateGuard checks rate limits and renders 429 page.
+type rateGuard struct {
+	*RateLimit
+	audit audit.Sink
+	view  *template.Template
+}
+
+// allow registers request in the scope and renders 429 page if rate exceeded.
+// Limiter errors are logged and request is allowed.
+func (rl *rateGuard) allow(request *web.Request, form string, scope string, rate ratelimit.Rate) bool {
+	if rl.Limiter == nil || !rate.Enabled() {
+		return true
+	}
+	ok, err := ratelimit.Allow(request.Context(), rl.Limiter, rl.key(request, form, scope), rate)
+	if err != nil {
+		request.Logger().Error("failed check rate limit - request allowed", "scope", scope, "error", err)
+		return true
+	}
+	if !ok {
+		rl.reject(request, form, scope, rate)
+	}
+	return ok
+}
+
+// undo removes request registered by allow in the scope, for example after successful attempt.
+func (rl *rateGuard) undo(request *web.Request, form string, scope string, rate ratelimit.Rate) {
+	if rl.Limiter == nil || !rate.Enabled() {
+		return
+	}
+	if err := rl.Limiter.Undo(context.WithoutCancel(request.Context()), rl.key(request, form, scope)); err != nil {
+		request.Logger().Error("failed undo rate limit hit", "scope", scope, "error", err)
+	}
+}
+
+func (rl *rateGuard) reject(request *web.Request, form string, scope string, rate ratelimit.Rate) {
+	metrics.RateLimited.WithLabelValues(form, scope).Inc()
+	recordAudit(rl.audit, request, audit.Event{Action: audit.ActionDenied, Form: form, Reason: audit.ReasonRate})
+	request.Logger().Info("rate limit exceeded", "scope", scope, "rate", rate.String())
+	request.Header().Set("Retry-After", strconv.Itoa(int(rate.Window.Seconds())))
+	request.Render(http.StatusTooManyRequests, rl.view)
+}
+
+func (rl *rateGuard) key(request *web.Request, form string, scope string) string {
+	client := "ip:" + web.GetClientIP(request.Request())
+	if user := request.Credentials().GetUser(); rl.ByUser && user != "" {
+		client = "user:" + user
+	}
+	if scope == scopeGlobal {
+		// shared across all forms
+		return scope + ":" + client
+	}
+	return scope + ":" + form + ":" + client
+}
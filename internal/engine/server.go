@@ -6,8 +6,13 @@ import (
 	"html/template"
 	"io/fs"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/reddec/web-form/internal/assets"
+	"github.com/reddec/web-form/internal/audit"
+	"github.com/reddec/web-form/internal/i18n"
+	"github.com/reddec/web-form/internal/limits"
 	"github.com/reddec/web-form/internal/schema"
 	"github.com/reddec/web-form/internal/utils"
 	"github.com/reddec/web-form/internal/web"
@@ -15,25 +20,89 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-var ErrDuplicatedName = errors.New("duplicated form name")
+var (
+	ErrDuplicatedName = errors.New("duplicated form name")
+	ErrFieldConflict  = errors.New("spam check field conflicts with form or reserved field")
+	ErrNoCodesStore   = errors.New("issued codes require codes store")
+	ErrUnknownCaptcha = errors.New("unknown captcha")
+	ErrUnknownView    = errors.New("unknown view")
+)
+
+// Page names which can be replaced by form theme.
+const (
+	PageForm      = "form"
+	PageSuccess   = "success"
+	PageFailed    = "failed"
+	PageAccess    = "access"
+	PageForbidden = "forbidden"
+	PageLimited   = "limited"
+	PageUpcoming  = "upcoming"
+	PageClosed    = "closed"
+)
+
+const defaultLayout = "form_base.gohtml"
+
+// default templates of form pages. Limited page is standalone and doesn't use layout.
+var pageFiles = map[string]string{
+	PageForm:      "form.gohtml",
+	PageSuccess:   "success.gohtml",
+	PageFailed:    "failed.gohtml",
+	PageAccess:    "access.gohtml",
+	PageForbidden: "forbidden.gohtml",
+	PageLimited:   "limited.gohtml",
+	PageUpcoming:  "upcoming.gohtml",
+	PageClosed:    "closed.gohtml",
+}
+
+// fields which pages with submittable forms must use, otherwise submissions are always rejected.
+var pageFields = map[string][]string{
+	PageForm:   {"EmbedXSRF", "EmbedSession", "EmbedSpamChecks", "EmbedCaptcha"},
+	PageAccess: {"EmbedXSRF", "EmbedSession", "EmbedCaptcha"},
+}
 
 type Config struct {
 	Forms           []schema.Form
 	Storage         Storage
 	WebhooksFactory WebhooksFactory
 	AMQPFactory     AMQPFactory
+	HooksFactory    HooksFactory
+	Audit           audit.Sink
+	AuditKey        []byte // key to fingerprint access codes in audit events, random if not set
+	Limits          limits.Store
+	Codes           CodesStore
+	Links           LinkVerifier
+	APIKeys         schema.APIKeys // global API keys
+	RateLimit       RateLimit
 	Listing         bool
-	Captcha         []web.Captcha
+	Captcha         map[string]web.Captcha // configured captchas by name
+	SpamChecks      []web.SpamCheck        // invisible checks of submissions
+	Locales         *i18n.Bundle           // message catalogs for UI, built-in by default
+	Views           fs.FS                  // templates for UI pages, built-in by default
 }
 
 func New(cfg Config, options ...FormOption) (http.Handler, error) {
-	views := assets.InsideViews()
-	listView := mustParse(views, "list.gohtml")
-	viewForm := mustParse(views, "form_base.gohtml", "form.gohtml")
-	viewSuccess := mustParse(views, "form_base.gohtml", "success.gohtml")
-	viewFail := mustParse(views, "form_base.gohtml", "failed.gohtml")
-	viewCode := mustParse(views, "form_base.gohtml", "access.gohtml")
-	viewForbidden := mustParse(views, "form_base.gohtml", "forbidden.gohtml")
+	if cfg.Views == nil {
+		cfg.Views = assets.InsideViews()
+	}
+	listView, err := assets.ParseView(cfg.Views, "list.gohtml")
+	if err != nil {
+		return nil, fmt.Errorf("list view: %w", err)
+	}
+	defaultViews, err := parsePages(cfg.Views, schema.Theme{})
+	if err != nil {
+		return nil, fmt.Errorf("form views: %w", err)
+	}
+
+	if cfg.Audit == nil {
+		cfg.Audit = audit.Nop{}
+	}
+	cfg.Audit = audit.HideCodes(cfg.Audit, cfg.AuditKey)
+	if cfg.Limits == nil {
+		cfg.Limits = limits.NewMemory()
+	}
+	if cfg.Locales == nil {
+		cfg.Locales = i18n.Default()
+	}
 
 	mux := chi.NewMux()
 
@@ -43,65 +112,178 @@ func New(cfg Config, options ...FormOption) (http.Handler, error) {
 			return nil, fmt.Errorf("form %q: %w", formDef.Name, ErrDuplicatedName)
 		}
 		usedName.Add(formDef.Name)
+		if formDef.IssuedCodes && cfg.Codes == nil {
+			return nil, fmt.Errorf("form %q: %w", formDef.Name, ErrNoCodesStore)
+		}
+		if err := checkSpamFields(formDef, cfg.SpamChecks); err != nil {
+			return nil, fmt.Errorf("form %q: %w", formDef.Name, err)
+		}
+		captcha, err := formCaptcha(formDef, cfg.Captcha)
+		if err != nil {
+			return nil, fmt.Errorf("form %q: %w", formDef.Name, err)
+		}
+		views := defaultViews
+		if formDef.Theme.Custom() {
+			views, err = parsePages(cfg.Views, formDef.Theme)
+			if err != nil {
+				return nil, fmt.Errorf("form %q: views: %w", formDef.Name, err)
+			}
+		}
 		mux.Mount("/forms/"+formDef.Name, NewForm(FormConfig{
 			Definition:      formDef,
-			ViewForm:        viewForm,
-			ViewSuccess:     viewSuccess,
-			ViewFail:        viewFail,
-			ViewCode:        viewCode,
-			ViewForbidden:   viewForbidden,
+			ViewForm:        views[PageForm],
+			ViewSuccess:     views[PageSuccess],
+			ViewFail:        views[PageFailed],
+			ViewCode:        views[PageAccess],
+			ViewForbidden:   views[PageForbidden],
+			ViewLimited:     views[PageLimited],
+			ViewUpcoming:    views[PageUpcoming],
+			ViewClosed:      views[PageClosed],
 			Storage:         cfg.Storage,
 			WebhooksFactory: cfg.WebhooksFactory,
 			AMQPFactory:     cfg.AMQPFactory,
-			Captcha:         cfg.Captcha,
+			HooksFactory:    cfg.HooksFactory,
+			Audit:           cfg.Audit,
+			Limits:          cfg.Limits,
+			Codes:           cfg.Codes,
+			Links:           cfg.Links,
+			APIKeys:         cfg.APIKeys,
+			RateLimit:       cfg.RateLimit,
+			Captcha:         captcha,
+			SpamChecks:      cfg.SpamChecks,
+			Locales:         cfg.Locales,
 		}, options...))
 	}
 	if cfg.Listing {
-		mux.Get("/", listViewHandler(cfg.Forms, listView))
+		keys := NewKeys(cfg.RateLimit.Limiter, cfg.RateLimit.APIKeys, cfg.APIKeys)
+		mux.Get("/", listViewHandler(cfg.Forms, listView, keys, cfg.Audit, cfg.Locales, &rateGuard{RateLimit: &cfg.RateLimit, audit: cfg.Audit, view: defaultViews[PageLimited]}))
 	}
 	return mux, nil
 }
 
-func listViewHandler(forms []schema.Form, listView *template.Template) http.HandlerFunc {
+// formCaptcha selects captcha for the form: all configured (sorted by name) by default, none, or one by name.
+func formCaptcha(formDef schema.Form, captchas map[string]web.Captcha) ([]web.Captcha, error) {
+	switch formDef.Captcha {
+	case "":
+		var names = make([]string, 0, len(captchas))
+		for name := range captchas {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		var ans = make([]web.Captcha, 0, len(names))
+		for _, name := range names {
+			ans = append(ans, captchas[name])
+		}
+		return ans, nil
+	case schema.CaptchaNone:
+		return nil, nil
+	default:
+		captcha, ok := captchas[formDef.Captcha]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownCaptcha, formDef.Captcha)
+		}
+		return []web.Captcha{captcha}, nil
+	}
+}
+
+// checkSpamFields ensures that fields embedded by spam checks are unique and do not replace form fields, access code,
+// XSRF token or session values.
+func checkSpamFields(formDef schema.Form, checks []web.SpamCheck) error {
+	var used = utils.NewSet[string]()
+	used.Add(accessCodeField)
+	used.Add(web.FormXSRF)
+	for _, field := range formDef.Fields {
+		used.Add(field.Name)
+	}
+	for _, check := range checks {
+		fields, ok := check.(web.SpamFields)
+		if !ok {
+			continue
+		}
+		for _, name := range fields.Fields() {
+			if used.Has(name) || web.IsSessionField(name) {
+				return fmt.Errorf("%s: %w: %q", check.Name(), ErrFieldConflict, name)
+			}
+			used.Add(name)
+		}
+	}
+	return nil
+}
+
+func listViewHandler(forms []schema.Form, listView *template.Template, keys *Keys, sink audit.Sink, locales *i18n.Bundle, rates *rateGuard) http.HandlerFunc {
+	for _, f := range forms {
+		locales = locales.With(f.Languages()...)
+	}
 	return func(writer http.ResponseWriter, request *http.Request) {
-		req := web.NewRequest(writer, request)
+		locale := localizer(locales, "", request)
+		req := web.NewRequest(writer, request).WithLocalizer(locale).Set("Static", "static")
+		if !rates.allow(req, "", scopeGlobal, rates.Global) {
+			return
+		}
+		// keys are expensive to verify, so they are checked after global rate limit
+		auth := keys.authenticateRequest(req)
+		if auth.limited {
+			rates.reject(req, "", scopeAPIKey, rates.APIKeys)
+			return
+		}
+		if !auth.allowed(request.Method) {
+			recordAudit(sink, req, audit.Event{Action: audit.ActionDenied, Reason: audit.ReasonAPIKey})
+			writer.Header().Set("WWW-Authenticate", "Bearer")
+			writer.WriteHeader(apiKeyStatus(auth))
+			return
+		}
 
-		creds := schema.CredentialsFromContext(request.Context())
+		policyContext := newPolicyContext(req)
+		now := time.Now()
 		var filteredForms = make([]schema.Form, 0, len(forms))
+		var availability = make(map[string]schema.Availability, len(forms))
 		for _, f := range forms {
-			if f.IsAllowed(creds) {
-				filteredForms = append(filteredForms, f)
+			state := f.Availability(now)
+			// closed forms will never be open again - no reason to show them
+			if f.Allows(policyContext) && state.Status != schema.StatusClosed {
+				filteredForms = append(filteredForms, f.Localize(locale.Language()))
+				availability[f.Name] = state
 			}
 		}
 
+		recordAudit(sink, req, audit.Event{Action: audit.ActionList})
 		req.Set("Definitions", filteredForms)
+		req.Set("Availability", availability)
 		req.Set("Context", newRequestContext(req))
 		req.Render(http.StatusOK, listView)
 	}
 }
 
-func mustParse(src fs.FS, base string, overlay ...string) *template.Template {
-	v, err := baseParse(src, base, overlay...)
-	if err != nil {
-		panic(err)
+// parsePages parses all form pages by name. Pages and layout from the theme replace default ones.
+func parsePages(src fs.FS, theme schema.Theme) (map[string]*template.Template, error) {
+	for name := range theme.Views {
+		if _, ok := pageFiles[name]; !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownView, name)
+		}
 	}
-	return v
-}
-
-func baseParse(src fs.FS, base string, overlay ...string) (*template.Template, error) {
-	var root = template.New("").Funcs(utils.TemplateFuncs())
-	var files = append([]string{base}, overlay...)
-
-	for _, file := range files {
-		content, err := fs.ReadFile(src, file)
-		if err != nil {
-			return nil, fmt.Errorf("read %q: %w", file, err)
+	layout := theme.Layout
+	if layout == "" {
+		layout = defaultLayout
+	}
+	var pages = make(map[string]*template.Template, len(pageFiles))
+	for name, file := range pageFiles {
+		if custom, ok := theme.Views[name]; ok {
+			file = custom
+		}
+		var view *template.Template
+		var err error
+		if name == PageLimited {
+			view, err = assets.ParseView(src, file)
+		} else {
+			view, err = assets.ParseView(src, layout, file)
 		}
-		sub, err := root.Parse(string(content))
 		if err != nil {
-			return nil, fmt.Errorf("parse %q: %w", file, err)
+			return nil, fmt.Errorf("page %q: %w", name, err)
+		}
+		if err := assets.RequireFields(view, pageFields[name]...); err != nil {
+			return nil, fmt.Errorf("page %q: %w", name, err)
 		}
-		root = sub
+		pages[name] = view
 	}
-	return root, nil
+	return pages, nil
 }
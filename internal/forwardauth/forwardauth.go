@@ -0,0 +1,110 @@
+// Package forwardauth builds credentials from headers set by trusted authentication proxy
+// (oauth2-proxy, Authelia, Authentik, etc.).
+package forwardauth
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/web"
+)
+
+type Config struct {
+	Enable    bool     `long:"enable" env:"ENABLE" description:"Enable authentication by headers from trusted proxy"`
+	User      string   `long:"user" env:"USER" description:"Header with user name" default:"X-Forwarded-User"`
+	Email     string   `long:"email" env:"EMAIL" description:"Header with user email" default:"X-Forwarded-Email"`
+	Groups    string   `long:"groups" env:"GROUPS" description:"Header with user groups" default:"X-Forwarded-Groups"`
+	Separator string   `long:"separator" env:"SEPARATOR" description:"Separator of groups in header" default:","`
+	Trusted   []string `long:"trusted" env:"TRUSTED" env-delim:"," description:"Trusted proxy IPs or CIDRs" default:"127.0.0.1/32" default:"::1/128"`
+	Anonymous bool     `long:"anonymous" env:"ANONYMOUS" description:"Allow requests from trusted proxy without user header"`
+}
+
+// New forward-auth middleware.
+func New(cfg Config) (*Auth, error) {
+	var trusted = make([]netip.Prefix, 0, len(cfg.Trusted))
+	for _, value := range cfg.Trusted {
+		prefix, err := web.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse trusted proxy %q: %w", value, err)
+		}
+		trusted = append(trusted, prefix)
+	}
+	if cfg.Separator == "" {
+		cfg.Separator = ","
+	}
+	return &Auth{config: cfg, trusted: trusted}, nil
+}
+
+type Auth struct {
+	config  Config
+	trusted []netip.Prefix
+}
+
+// Secure handler: requests not from trusted proxies are rejected with 403, and requests without user (unless
+// anonymous allowed) are rejected with 401. Credentials are added to request context.
+func (a *Auth) Secure(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !a.IsTrusted(request) {
+			slog.Warn("request not from trusted proxy", "remote_addr", request.RemoteAddr, "path", request.URL.Path)
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+		creds := a.Credentials(request)
+		if creds == nil {
+			if !a.config.Anonymous {
+				writer.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(writer, request)
+			return
+		}
+		next.ServeHTTP(writer, request.WithContext(schema.WithCredentials(request.Context(), creds)))
+	})
+}
+
+// IsTrusted checks that request came directly from trusted proxy.
+func (a *Auth) IsTrusted(request *http.Request) bool {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range a.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Credentials from headers. Returns nil if there is no user.
+func (a *Auth) Credentials(request *http.Request) *schema.Credentials {
+	user := strings.TrimSpace(request.Header.Get(a.config.User))
+	email := strings.TrimSpace(request.Header.Get(a.config.Email))
+	if user == "" {
+		user = email
+	}
+	if user == "" {
+		return nil
+	}
+	var groups []string
+	for _, group := range strings.Split(request.Header.Get(a.config.Groups), a.config.Separator) {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return &schema.Credentials{
+		User:   user,
+		Email:  email,
+		Groups: groups,
+	}
+}
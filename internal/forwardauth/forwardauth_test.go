@@ -0,0 +1,65 @@
+package forwardauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddec/web-form/internal/forwardauth"
+	"github.com/reddec/web-form/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_Secure(t *testing.T) {
+	auth, err := forwardauth.New(forwardauth.Config{
+		User:    "X-Forwarded-User",
+		Email:   "X-Forwarded-Email",
+		Groups:  "X-Forwarded-Groups",
+		Trusted: []string{"10.0.0.0/8", "::1"},
+	})
+	require.NoError(t, err)
+
+	var creds *schema.Credentials
+	handler := auth.Secure(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		creds = schema.CredentialsFromContext(request.Context())
+	}))
+
+	call := func(remoteAddr string, headers map[string]string) int {
+		creds = nil
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	code := call("10.1.2.3:1234", map[string]string{
+		"X-Forwarded-User":   "alice",
+		"X-Forwarded-Email":  "alice@example.com",
+		"X-Forwarded-Groups": "admin, dev,,",
+	})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, &schema.Credentials{User: "alice", Email: "alice@example.com", Groups: []string{"admin", "dev"}}, creds)
+
+	code = call("[::1]:1234", map[string]string{"X-Forwarded-Email": "bob@example.com"})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "bob@example.com", creds.User)
+
+	// headers from untrusted source are not accepted
+	code = call("192.0.2.1:1234", map[string]string{"X-Forwarded-User": "alice"})
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Nil(t, creds)
+
+	code = call("10.1.2.3:1234", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestNew(t *testing.T) {
+	_, err := forwardauth.New(forwardauth.Config{Trusted: []string{"not-an-ip"}})
+	assert.Error(t, err)
+}
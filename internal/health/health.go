@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Check returns nil if dependency is healthy.
+type Check func(ctx context.Context) error
+
+// Queue exposes current depth and capacity of internal queue.
+type Queue interface {
+	Pending() int
+	Capacity() int
+}
+
+// Saturation check fails if queue filled more than threshold (0..1).
+func Saturation(queue Queue, threshold float64) Check {
+	return func(context.Context) error {
+		capacity := queue.Capacity()
+		if capacity <= 0 {
+			return nil
+		}
+		pending := queue.Pending()
+		if float64(pending)/float64(capacity) > threshold {
+			return fmt.Errorf("queue saturated: %d of %d", pending, capacity)
+		}
+		return nil
+	}
+}
+
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"-"` // details are logged, but not exposed to clients
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// New registry of readiness checks. Zero or negative timeout means default (5s).
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Checker{timeout: timeout, checks: make(map[string]Check)}
+}
+
+type Checker struct {
+	timeout time.Duration
+	checks  map[string]Check
+}
+
+// Add named check. Not thread-safe, should be called before serving.
+func (c *Checker) Add(name string, check Check) {
+	c.checks[name] = check
+}
+
+// Run all checks in parallel.
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var report = Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range c.checks {
+		name, check := name, check
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var res = Result{Status: StatusOK}
+			if err := check(ctx); err != nil {
+				res = Result{Status: StatusFail, Error: err.Error()}
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			report.Checks[name] = res
+			if res.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// Liveness handler always returns OK while process is able to serve requests.
+func Liveness(writer http.ResponseWriter, _ *http.Request) {
+	writeReport(writer, Report{Status: StatusOK})
+}
+
+// Readiness handler runs all checks and returns 503 if any of them failed. Reasons of failures are logged only.
+func (c *Checker) Readiness(writer http.ResponseWriter, request *http.Request) {
+	report := c.Run(request.Context())
+	for name, res := range report.Checks {
+		if res.Status != StatusOK {
+			slog.Warn("readiness check failed", "check", name, "error", res.Error)
+		}
+	}
+	writeReport(writer, report)
+}
+
+func writeReport(writer http.ResponseWriter, report Report) {
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(code)
+	_ = json.NewEncoder(writer).Encode(report)
+}
@@ -0,0 +1,62 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddec/web-form/internal/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Readiness(t *testing.T) {
+	checker := health.New(0)
+	checker.Add("good", func(ctx context.Context) error { return nil })
+
+	t.Run("ready", func(t *testing.T) {
+		report := readiness(t, checker, http.StatusOK)
+		assert.Equal(t, health.StatusOK, report.Status)
+		assert.Equal(t, health.StatusOK, report.Checks["good"].Status)
+	})
+
+	checker.Add("queue", health.Saturation(&queue{pending: 10, capacity: 10}, 0.9))
+	checker.Add("bad", func(ctx context.Context) error { return errors.New("broken") })
+
+	t.Run("not ready", func(t *testing.T) {
+		report := readiness(t, checker, http.StatusServiceUnavailable)
+		assert.Equal(t, health.StatusFail, report.Status)
+		assert.Equal(t, health.StatusOK, report.Checks["good"].Status)
+		assert.Equal(t, health.StatusFail, report.Checks["queue"].Status)
+		assert.Equal(t, health.Result{Status: health.StatusFail}, report.Checks["bad"])
+	})
+
+	t.Run("errors are not exposed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		checker.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.NotContains(t, rec.Body.String(), "broken")
+		assert.NotContains(t, rec.Body.String(), "saturated")
+		assert.Equal(t, "broken", checker.Run(context.Background()).Checks["bad"].Error)
+	})
+}
+
+func readiness(t *testing.T, checker *health.Checker, code int) health.Report {
+	rec := httptest.NewRecorder()
+	checker.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, code, rec.Code)
+	var report health.Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	return report
+}
+
+type queue struct {
+	pending  int
+	capacity int
+}
+
+func (q *queue) Pending() int { return q.pending }
+
+func (q *queue) Capacity() int { return q.capacity }
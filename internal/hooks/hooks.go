@@ -0,0 +1,230 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/reddec/web-form/internal/schema"
+)
+
+var (
+	ErrNonSuccessCode = errors.New("non-2xx response code")
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	defaultMethod  = http.MethodPost
+	maxResponse    = 1024 * 1024 // 1MB
+)
+
+// Hook is synchronous call performed before storing submission.
+type Hook interface {
+	Call(ctx context.Context, event Event) (*Result, error)
+}
+
+// Event is payload sent to the hook.
+type Event struct {
+	Form   string         `json:"form"`
+	User   string         `json:"user,omitempty"`
+	Lang   string         `json:"lang,omitempty"` // UI language of the client, can be used to localize errors
+	Values map[string]any `json:"values"`
+}
+
+// Result of hook. Errors key is field name, empty name means general (form-level) error.
+// Values (if any) are validated and merged to the submission values, see Apply.
+type Result struct {
+	Values map[string]any    `json:"values,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// FieldErrors converts hook errors to field errors, sorted by field name.
+func (r *Result) FieldErrors() []schema.FieldError {
+	var names = make([]string, 0, len(r.Errors))
+	for name := range r.Errors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var ans = make([]schema.FieldError, 0, len(names))
+	for _, name := range names {
+		ans = append(ans, schema.FieldError{
+			Name:  name,
+			Error: errors.New(r.Errors[name]),
+		})
+	}
+	return ans
+}
+
+// Apply hook values to the submission. Values are parsed and validated the same way as user input of form fields.
+// Values of unknown fields (as form-level errors) and invalid values are returned as field errors, in that case
+// submission is not changed.
+func (r *Result) Apply(values map[string]any, fields []schema.Field, tzLocation *time.Location, viewCtx *schema.RequestContext) []schema.FieldError {
+	var names = make([]string, 0, len(r.Values))
+	for name := range r.Values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var parsed = make(map[string]any, len(names))
+	var unset []string
+	var fieldErrors []schema.FieldError
+	for _, name := range names {
+		idx := slices.IndexFunc(fields, func(field schema.Field) bool { return field.Name == name })
+		if idx < 0 {
+			// form-level error, since there is no field to show error next to
+			fieldErrors = append(fieldErrors, schema.FieldError{Error: fmt.Errorf("%w: %q", schema.ErrUnknownField, name)})
+			continue
+		}
+		value, ok, err := parseValue(&fields[idx], r.Values[name], tzLocation, viewCtx)
+		if err != nil {
+			fieldErrors = append(fieldErrors, schema.FieldError{Name: name, Error: err})
+			continue
+		}
+		if ok {
+			parsed[name] = value
+		} else {
+			unset = append(unset, name)
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return fieldErrors
+	}
+	for name, value := range parsed {
+		values[name] = value
+	}
+	for _, name := range unset {
+		delete(values, name)
+	}
+	return nil
+}
+
+// parseValue converts JSON value of the field to raw values and parses them as user input.
+func parseValue(field *schema.Field, value any, tzLocation *time.Location, viewCtx *schema.RequestContext) (any, bool, error) {
+	var raw []string
+	switch v := value.(type) {
+	case nil:
+	case []any:
+		if !field.Multiple {
+			return nil, false, schema.ErrInvalidValue
+		}
+		for _, item := range v {
+			text, err := toText(item)
+			if err != nil {
+				return nil, false, err
+			}
+			raw = append(raw, text)
+		}
+	default:
+		text, err := toText(v)
+		if err != nil {
+			return nil, false, err
+		}
+		raw = append(raw, text)
+	}
+	return schema.ParseValues(field, raw, tzLocation, viewCtx)
+}
+
+func toText(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", schema.ErrInvalidValue
+	}
+}
+
+type Option func(factory *Factory)
+
+// WithClient sets HTTP client for hooks. Default is http.DefaultClient.
+func WithClient(client *http.Client) Option {
+	return func(factory *Factory) {
+		factory.client = client
+	}
+}
+
+func New(options ...Option) *Factory {
+	f := &Factory{client: http.DefaultClient}
+	for _, opt := range options {
+		opt(f)
+	}
+	return f
+}
+
+type Factory struct {
+	client *http.Client
+}
+
+func (f *Factory) Create(hook schema.Hook) Hook {
+	if hook.Timeout <= 0 {
+		hook.Timeout = defaultTimeout
+	}
+	if hook.Method == "" {
+		hook.Method = defaultMethod
+	}
+	return &httpHook{hook: hook, client: f.client}
+}
+
+type httpHook struct {
+	hook   schema.Hook
+	client *http.Client
+}
+
+func (hh *httpHook) Call(global context.Context, event Event) (*Result, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("encode payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(global, hh.hook.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, hh.hook.Method, hh.hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range hh.hook.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := hh.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		_, _ = io.Copy(io.Discard, res.Body) // drain content to keep connection healthy
+		return nil, fmt.Errorf("%w: %d", ErrNonSuccessCode, res.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxResponse))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var result Result
+	if len(bytes.TrimSpace(data)) == 0 {
+		// empty response means no changes
+		return &result, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // keep numbers as is, they are parsed by field type
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &result, nil
+}
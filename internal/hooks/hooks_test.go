@@ -0,0 +1,142 @@
+package hooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/hooks"
+	"github.com/reddec/web-form/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFactory_Create(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("enrich", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			var event hooks.Event
+			require.NoError(t, json.NewDecoder(request.Body).Decode(&event))
+			assert.Equal(t, "orders", event.Form)
+			assert.Equal(t, "Bearer foo", request.Header.Get("Authorization"))
+			_ = json.NewEncoder(writer).Encode(map[string]any{
+				"values": map[string]any{"customer": event.Values["order"].(string) + "-customer", "id": 1, "admin": true},
+			})
+		}))
+		defer server.Close()
+
+		hook := hooks.New().Create(schema.Hook{
+			URL:     server.URL,
+			Headers: map[string]string{"Authorization": "Bearer foo"},
+		})
+		values := map[string]any{"order": "123"}
+		res, err := hook.Call(ctx, hooks.Event{Form: "orders", Values: values})
+		require.NoError(t, err)
+		require.Empty(t, res.FieldErrors())
+		errs := res.Apply(values, []schema.Field{{Name: "order"}, {Name: "customer"}, {Name: "id", Type: schema.TypeInteger}, {Name: "admin", Type: schema.TypeBoolean}}, time.UTC, &schema.RequestContext{})
+		require.Empty(t, errs)
+		assert.Equal(t, map[string]any{"order": "123", "customer": "123-customer", "id": int64(1), "admin": true}, values)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		res := &hooks.Result{Values: map[string]any{
+			"order":   json.Number("1.5"),
+			"tags":    []any{"a", "b"},
+			"unknown": "x",
+			"color":   "blue",
+			"name":    "",
+		}}
+		fields := []schema.Field{
+			{Name: "order", Type: schema.TypeInteger},
+			{Name: "tags"},
+			{Name: "color", Options: []schema.Option{{Label: "red"}}},
+			{Name: "name", Required: true},
+		}
+		values := map[string]any{"order": int64(1)}
+		errs := res.Apply(values, fields, time.UTC, &schema.RequestContext{})
+		require.Len(t, errs, 5)
+		assert.Equal(t, "color", errs[0].Name)
+		assert.ErrorIs(t, errs[0].Error, schema.ErrInvalidOption)
+		assert.Equal(t, "name", errs[1].Name)
+		assert.ErrorIs(t, errs[1].Error, schema.ErrRequiredField)
+		assert.Equal(t, "order", errs[2].Name)
+		assert.Equal(t, "tags", errs[3].Name)
+		assert.ErrorIs(t, errs[3].Error, schema.ErrInvalidValue)
+		assert.Equal(t, "", errs[4].Name)
+		assert.ErrorIs(t, errs[4].Error, schema.ErrUnknownField)
+		assert.Equal(t, map[string]any{"order": int64(1)}, values, "submission is not changed")
+	})
+
+	t.Run("event values round trip", func(t *testing.T) {
+		at := time.Date(2024, 5, 6, 7, 8, 0, 0, time.UTC)
+		data, err := json.Marshal(map[string]any{"values": map[string]any{"at": at, "tags": []string{"a", "b"}}})
+		require.NoError(t, err)
+		var res hooks.Result
+		require.NoError(t, json.Unmarshal(data, &res))
+		values := map[string]any{}
+		errs := res.Apply(values, []schema.Field{{Name: "at", Type: schema.TypeDateTime}, {Name: "tags", Multiple: true}}, time.UTC, &schema.RequestContext{})
+		require.Empty(t, errs)
+		assert.True(t, at.Equal(values["at"].(time.Time)))
+		assert.Equal(t, []any{"a", "b"}, values["tags"])
+	})
+
+	t.Run("field errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			_, _ = writer.Write([]byte(`{"errors":{"order":"unknown order","amount":"too big","":"try later"}}`))
+		}))
+		defer server.Close()
+
+		hook := hooks.New().Create(schema.Hook{URL: server.URL})
+		res, err := hook.Call(ctx, hooks.Event{Form: "orders", Values: map[string]any{"order": "123"}})
+		require.NoError(t, err)
+		errs := res.FieldErrors()
+		require.Len(t, errs, 3)
+		assert.Equal(t, "", errs[0].Name)
+		assert.Equal(t, "amount", errs[1].Name)
+		assert.Equal(t, "order", errs[2].Name)
+		assert.EqualError(t, errs[2].Error, "unknown order")
+	})
+
+	t.Run("empty response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		hook := hooks.New().Create(schema.Hook{URL: server.URL})
+		res, err := hook.Call(ctx, hooks.Event{Form: "orders"})
+		require.NoError(t, err)
+		assert.Empty(t, res.Values)
+		assert.Empty(t, res.Errors)
+	})
+
+	t.Run("non-2xx code", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		hook := hooks.New().Create(schema.Hook{URL: server.URL})
+		_, err := hook.Call(ctx, hooks.Event{Form: "orders"})
+		require.ErrorIs(t, err, hooks.ErrNonSuccessCode)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			select {
+			case <-request.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		defer server.Close()
+
+		hook := hooks.New().Create(schema.Hook{URL: server.URL, Timeout: 50 * time.Millisecond})
+		_, err := hook.Call(ctx, hooks.Event{Form: "orders"})
+		require.Error(t, err)
+	})
+}
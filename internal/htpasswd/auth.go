@@ -0,0 +1,172 @@
+// Package htpasswd provides login page backed by htpasswd (bcrypt) and optional group files.
+package htpasswd
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/reddec/web-form/internal/assets"
+	"github.com/reddec/web-form/internal/i18n"
+	"github.com/reddec/web-form/internal/ratelimit"
+	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/web"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	Prefix      = "/auth" // where login and logout pages are mounted
+	sessionUser = "htpasswd-user"
+)
+
+type Option func(auth *Auth)
+
+// WithRateLimit limits failed login attempts per client IP.
+func WithRateLimit(limiter ratelimit.Limiter, rate ratelimit.Rate) Option {
+	return func(auth *Auth) {
+		auth.limiter = limiter
+		auth.rate = rate
+	}
+}
+
+// WithLocales sets message catalogs of the login page, built-in by default.
+func WithLocales(locales *i18n.Bundle) Option {
+	return func(auth *Auth) {
+		auth.locales = locales
+	}
+}
+
+// WithView replaces built-in login page.
+func WithView(view *template.Template) Option {
+	return func(auth *Auth) {
+		auth.view = view
+	}
+}
+
+// New authentication by users from htpasswd file. Groups are optional.
+// Auth should be mounted to Prefix.
+func New(users Users, groups Groups, sessions *scs.SessionManager, options ...Option) *Auth {
+	a := &Auth{
+		users:    users,
+		groups:   groups,
+		sessions: sessions,
+		mux:      chi.NewMux(),
+	}
+	for _, opt := range options {
+		opt(a)
+	}
+	if a.locales == nil {
+		a.locales = i18n.Default()
+	}
+	if a.view == nil {
+		a.view = template.Must(assets.ParseView(assets.InsideViews(), "login.gohtml"))
+	}
+	a.mux.Get("/login", a.showLogin)
+	a.mux.Post("/login", a.login)
+	a.mux.Get("/logout", a.logout)
+	a.mux.Post("/logout", a.logout)
+	return a
+}
+
+type Auth struct {
+	users    Users
+	groups   Groups
+	sessions *scs.SessionManager
+	view     *template.Template
+	locales  *i18n.Bundle
+	limiter  ratelimit.Limiter
+	rate     ratelimit.Rate
+	mux      *chi.Mux
+}
+
+func (a *Auth) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	a.mux.ServeHTTP(writer, request)
+}
+
+// Secure handler: requests without session are redirected to login page. Credentials are added to request context.
+func (a *Auth) Secure(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		user := a.sessions.GetString(request.Context(), sessionUser)
+		if _, exists := a.users[user]; !exists { // also logout removed users
+			http.Redirect(writer, request, Prefix+"/login?redirect="+url.QueryEscape(request.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		creds := &schema.Credentials{
+			User:   user,
+			Groups: a.groups[user],
+		}
+		next.ServeHTTP(writer, request.WithContext(schema.WithCredentials(request.Context(), creds)))
+	})
+}
+
+func (a *Auth) showLogin(writer http.ResponseWriter, request *http.Request) {
+	web.NewRequest(writer, request).
+		WithLocalizer(a.localizer(request)).
+		Set("Redirect", web.LocalRedirect(request.URL.Query().Get("redirect"))).
+		Render(http.StatusOK, a.view)
+}
+
+func (a *Auth) login(writer http.ResponseWriter, request *http.Request) {
+	req := web.NewRequest(writer, request).WithLocalizer(a.localizer(request))
+	user := request.PostFormValue("username")
+	redirect := web.LocalRedirect(request.PostFormValue("redirect"))
+	req.Set("Redirect", redirect).Set("Username", user)
+
+	if !req.VerifyXSRF() {
+		req.Error(req.T("error.xsrf"))
+		req.Render(http.StatusForbidden, a.view)
+		return
+	}
+
+	ctx := request.Context()
+	key := "login:" + web.GetClientIP(request)
+	// attempt is counted before check, so parallel guesses can't overrun the limit, and returned back on success
+	limited := a.limiter != nil && a.rate.Enabled()
+	if limited {
+		ok, err := ratelimit.Allow(ctx, a.limiter, key, a.rate)
+		if err != nil {
+			req.Logger().Error("failed check login rate limit - request allowed", "error", err)
+		} else if !ok {
+			req.Error(req.T("login.limited"))
+			req.Render(http.StatusTooManyRequests, a.view)
+			return
+		}
+	}
+
+	if !a.users.Verify(user, request.PostFormValue("password")) {
+		req.Logger().Info("failed login", "username", user)
+		req.Error(req.T("login.invalid"))
+		req.Render(http.StatusUnauthorized, a.view)
+		return
+	}
+	if limited {
+		if err := a.limiter.Undo(context.WithoutCancel(ctx), key); err != nil {
+			req.Logger().Error("failed undo login rate limit hit", "error", err)
+		}
+	}
+
+	// prevent session fixation
+	if err := a.sessions.RenewToken(ctx); err != nil {
+		req.Logger().Error("failed renew session token", "error", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	a.sessions.Put(ctx, sessionUser, user)
+	req.Logger().Info("user logged in", "username", user)
+	http.Redirect(writer, request, redirect, http.StatusSeeOther)
+}
+
+// localizer selects language of the page by client preferences.
+func (a *Auth) localizer(request *http.Request) *i18n.Localizer {
+	return a.locales.Localizer(request.Header.Get("Accept-Language"))
+}
+
+func (a *Auth) logout(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	a.sessions.Remove(ctx, sessionUser)
+	_ = a.sessions.RenewToken(ctx)
+	http.Redirect(writer, request, Prefix+"/login", http.StatusSeeOther)
+}
@@ -0,0 +1,107 @@
+package htpasswd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported password hash, only bcrypt is allowed")
+
+// dummyHash is used to keep response time the same for unknown users.
+//
+//nolint:gochecknoglobals
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// Users from htpasswd file: user name -> bcrypt hash.
+type Users map[string][]byte
+
+// Verify user password. Unknown users take the same time as known.
+func (u Users) Verify(user, password string) bool {
+	hash, ok := u[user]
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// Groups of users: user name -> groups.
+type Groups map[string][]string
+
+// ReadUsers parses htpasswd content (user:hash per line). Only bcrypt hashes are supported (htpasswd -B).
+func ReadUsers(reader io.Reader) (Users, error) {
+	var users = make(Users)
+	err := scanLines(reader, func(line string) error {
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return fmt.Errorf("invalid line %q", line)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("user %q: %w", user, ErrUnsupportedHash)
+		}
+		users[user] = []byte(hash)
+		return nil
+	})
+	return users, err
+}
+
+// ReadGroups parses Apache group file (group: user1 user2 per line).
+func ReadGroups(reader io.Reader) (Groups, error) {
+	var groups = make(Groups)
+	err := scanLines(reader, func(line string) error {
+		group, members, ok := strings.Cut(line, ":")
+		group = strings.TrimSpace(group)
+		if !ok || group == "" {
+			return fmt.Errorf("invalid line %q", line)
+		}
+		for _, user := range strings.Fields(members) {
+			groups[user] = append(groups[user], group)
+		}
+		return nil
+	})
+	return groups, err
+}
+
+// LoadUsers reads htpasswd file.
+func LoadUsers(file string) (Users, error) {
+	return load(file, ReadUsers)
+}
+
+// LoadGroups reads group file.
+func LoadGroups(file string) (Groups, error) {
+	return load(file, ReadGroups)
+}
+
+func load[T any](file string, parser func(io.Reader) (T, error)) (T, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer f.Close()
+	v, err := parser(f)
+	if err != nil {
+		return v, fmt.Errorf("parse %q: %w", file, err)
+	}
+	return v, nil
+}
+
+func scanLines(reader io.Reader, handler func(line string) error) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := handler(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
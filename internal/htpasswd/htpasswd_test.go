@@ -0,0 +1,123 @@
+package htpasswd_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/htpasswd"
+	"github.com/reddec/web-form/internal/ratelimit"
+	"github.com/reddec/web-form/internal/schema"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestReadUsers(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	users, err := htpasswd.ReadUsers(strings.NewReader("# comment\n\nalice:" + string(hash) + "\n"))
+	require.NoError(t, err)
+	assert.True(t, users.Verify("alice", "secret"))
+	assert.False(t, users.Verify("alice", "wrong"))
+	assert.False(t, users.Verify("bob", "secret"))
+
+	_, err = htpasswd.ReadUsers(strings.NewReader("bob:$apr1$salt$hash\n"))
+	assert.ErrorIs(t, err, htpasswd.ErrUnsupportedHash)
+
+	groups, err := htpasswd.ReadGroups(strings.NewReader("admin: alice\ndev: alice bob\n"))
+	require.NoError(t, err)
+	assert.Equal(t, htpasswd.Groups{"alice": {"admin", "dev"}, "bob": {"dev"}}, groups)
+}
+
+func TestAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	sessions := scs.New()
+	auth := htpasswd.New(
+		htpasswd.Users{"alice": hash},
+		htpasswd.Groups{"alice": {"admin"}},
+		sessions,
+		htpasswd.WithRateLimit(ratelimit.NewMemory(), ratelimit.Rate{Limit: 2, Window: time.Minute}),
+	)
+
+	var creds *schema.Credentials
+	router := chi.NewRouter()
+	router.Use(sessions.LoadAndSave)
+	router.Mount(htpasswd.Prefix, auth)
+	router.With(auth.Secure).Get("/forms/demo", func(writer http.ResponseWriter, request *http.Request) {
+		creds = schema.CredentialsFromContext(request.Context())
+	})
+
+	var cookies []*http.Cookie
+	call := func(method, path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = "192.0.2.1:1234"
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		cookies = append(cookies, rec.Result().Cookies()...)
+		return rec
+	}
+	login := func(password string) *httptest.ResponseRecorder {
+		return call(http.MethodPost, "/auth/login", url.Values{
+			"_xsrf":    {"demo"},
+			"username": {"alice"},
+			"password": {password},
+			"redirect": {"/forms/demo"},
+		})
+	}
+	cookies = append(cookies, &http.Cookie{Name: "_xsrf", Value: "demo"})
+
+	rec := call(http.MethodGet, "/forms/demo", nil)
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/auth/login?redirect=%2Fforms%2Fdemo", rec.Header().Get("Location"))
+
+	rec = call(http.MethodGet, "/auth/login?redirect=%2Fforms%2Fdemo", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `value="/forms/demo"`)
+
+	rec = login("wrong")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<html lang="en">`)
+	assert.Contains(t, rec.Body.String(), "invalid username or password")
+
+	rec = login("secret")
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/forms/demo", rec.Header().Get("Location"))
+
+	rec = call(http.MethodGet, "/forms/demo", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, &schema.Credentials{User: "alice", Groups: []string{"admin"}}, creds)
+
+	rec = call(http.MethodPost, "/auth/logout", nil)
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	rec = call(http.MethodGet, "/forms/demo", nil)
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+
+	// brute-force protection
+	login("wrong")
+	rec = login("secret")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "too many failed attempts")
+
+	// localized by client preferences
+	req := httptest.NewRequest(http.MethodGet, "/auth/login", nil)
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<html lang="de">`)
+	assert.Contains(t, rec.Body.String(), "Benutzername")
+}
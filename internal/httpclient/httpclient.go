@@ -0,0 +1,156 @@
+// Package httpclient builds shared client for outgoing requests (webhooks, hooks, captcha validation) with proxy,
+// custom CA, client certificates (mTLS) and per-destination timeouts.
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTimeout = errors.New("invalid timeout, expected format <host>=<duration>, for example hooks.example.com=5s")
+
+type Config struct {
+	Proxy    string        `long:"proxy" env:"PROXY" description:"Proxy URL for outgoing requests. If not set - HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used"`
+	CA       []string      `long:"ca" env:"CA" env-delim:"," description:"Additional PEM files with trusted CA certificates"`
+	Cert     string        `long:"cert" env:"CERT" description:"Client TLS certificate (PEM) for mTLS"`
+	Key      string        `long:"key" env:"KEY" description:"Client TLS private key (PEM) for mTLS"`
+	Timeout  time.Duration `long:"timeout" env:"TIMEOUT" description:"Default timeout for outgoing requests. Disabled if zero" default:"30s"`
+	Timeouts []Timeout     `long:"timeouts" env:"TIMEOUTS" env-delim:"," description:"Timeouts per destination host, for example hooks.example.com=5s"`
+}
+
+// Timeout for requests to the host (with or without port).
+type Timeout struct {
+	Host    string
+	Timeout time.Duration
+}
+
+func (t *Timeout) UnmarshalText(text []byte) error {
+	host, value, ok := strings.Cut(string(text), "=")
+	if !ok || strings.TrimSpace(host) == "" {
+		return ErrInvalidTimeout
+	}
+	timeout, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || timeout < 0 {
+		return fmt.Errorf("%w: duration: %q", ErrInvalidTimeout, value)
+	}
+	t.Host = strings.ToLower(strings.TrimSpace(host))
+	t.Timeout = timeout
+	return nil
+}
+
+// UnmarshalFlag implements go-flags Unmarshaler.
+func (t *Timeout) UnmarshalFlag(value string) error {
+	return t.UnmarshalText([]byte(value))
+}
+
+// New HTTP client by config.
+func New(cfg Config) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(cfg.CA) > 0 {
+		pool, err := loadCA(cfg.CA)
+		if err != nil {
+			return nil, fmt.Errorf("load CA: %w", err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.Cert != "" || cfg.Key != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	var timeouts = make(map[string]time.Duration, len(cfg.Timeouts))
+	for _, t := range cfg.Timeouts {
+		timeouts[t.Host] = t.Timeout
+	}
+
+	return &http.Client{
+		Transport: &timeoutTransport{
+			next:     transport,
+			timeout:  cfg.Timeout,
+			timeouts: timeouts,
+		},
+	}, nil
+}
+
+// system CA with additional certificates.
+func loadCA(files []string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read %q: %w", file, err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in %q", file)
+		}
+	}
+	return pool, nil
+}
+
+// timeoutTransport limits whole request (including reading body) by timeout of destination host or default one.
+type timeoutTransport struct {
+	next     http.RoundTripper
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+}
+
+func (tt *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout := tt.timeoutFor(req.URL)
+	if timeout <= 0 {
+		return tt.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	res, err := tt.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+func (tt *timeoutTransport) timeoutFor(u *url.URL) time.Duration {
+	if v, ok := tt.timeouts[strings.ToLower(u.Host)]; ok {
+		return v
+	}
+	if v, ok := tt.timeouts[strings.ToLower(u.Hostname())]; ok {
+		return v
+	}
+	return tt.timeout
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (cb *cancelBody) Close() error {
+	defer cb.cancel()
+	return cb.ReadCloser.Close()
+}
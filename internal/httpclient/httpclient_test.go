@@ -0,0 +1,125 @@
+package httpclient_test
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/httpclient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout_UnmarshalText(t *testing.T) {
+	var v httpclient.Timeout
+	require.NoError(t, v.UnmarshalText([]byte("Hooks.Example.com:8443=5s")))
+	assert.Equal(t, "hooks.example.com:8443", v.Host)
+	assert.Equal(t, 5*time.Second, v.Timeout)
+
+	for _, bad := range []string{"", "example.com", "=5s", "example.com=abc", "example.com=-1s"} {
+		assert.ErrorIs(t, v.UnmarshalText([]byte(bad)), httpclient.ErrInvalidTimeout, bad)
+	}
+}
+
+func TestNew_timeouts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-request.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	t.Run("per host", func(t *testing.T) {
+		client, err := httpclient.New(httpclient.Config{
+			Timeout:  time.Minute,
+			Timeouts: []httpclient.Timeout{{Host: u.Host, Timeout: 50 * time.Millisecond}},
+		})
+		require.NoError(t, err)
+		started := time.Now()
+		_, err = client.Get(srv.URL)
+		require.Error(t, err)
+		assert.Less(t, time.Since(started), time.Second)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		client, err := httpclient.New(httpclient.Config{
+			Timeout:  50 * time.Millisecond,
+			Timeouts: []httpclient.Timeout{{Host: "other.example.com", Timeout: time.Minute}},
+		})
+		require.NoError(t, err)
+		_, err = client.Get(srv.URL)
+		require.Error(t, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		client, err := httpclient.New(httpclient.Config{})
+		require.NoError(t, err)
+		res, err := client.Get(srv.URL)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
+
+func TestNew_proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		proxied = request.URL.String()
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	client, err := httpclient.New(httpclient.Config{Proxy: proxy.URL})
+	require.NoError(t, err)
+
+	res, err := client.Get("http://hooks.example.com/notify")
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Equal(t, "http://hooks.example.com/notify", proxied)
+}
+
+func TestNew_ca(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	t.Run("untrusted", func(t *testing.T) {
+		client, err := httpclient.New(httpclient.Config{})
+		require.NoError(t, err)
+		_, err = client.Get(srv.URL)
+		require.Error(t, err)
+	})
+
+	t.Run("trusted", func(t *testing.T) {
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		require.NoError(t, os.WriteFile(caFile, data, 0600))
+
+		client, err := httpclient.New(httpclient.Config{CA: []string{caFile}})
+		require.NoError(t, err)
+		res, err := client.Get(srv.URL)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+		_, err := httpclient.New(httpclient.Config{CA: []string{caFile}})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,223 @@
+// Package i18n provides message catalogs for UI and selects language by form locale or Accept-Language header.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/reddec/web-form/internal/assets"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLanguage is used when client's language is not supported.
+const DefaultLanguage = "en"
+
+var ErrUnknownLanguage = errors.New("unknown language")
+
+// Messages is catalog of translated messages by key. Messages may contain fmt verbs.
+type Messages map[string]string
+
+// Bundle of catalogs for all supported languages.
+type Bundle struct {
+	fallback  string
+	defaults  Messages // messages of fallback language, completed by DefaultLanguage
+	catalogs  map[string]Messages
+	languages []string // supported languages, fallback is always first
+	matcher   language.Matcher
+}
+
+// New bundle from catalogs by language. Fallback language should be in catalogs, messages missing in other catalogs
+// are taken from it, and then from DefaultLanguage (if present).
+func New(fallback string, catalogs map[string]Messages) (*Bundle, error) {
+	fallback = Normalize(fallback)
+	if _, ok := catalogs[fallback]; !ok {
+		return nil, fmt.Errorf("fallback %q: %w", fallback, ErrUnknownLanguage)
+	}
+
+	var languages = make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		if lang != fallback {
+			languages = append(languages, lang)
+		}
+	}
+	slices.Sort(languages)
+	languages = append([]string{fallback}, languages...)
+
+	var tags = make([]language.Tag, 0, len(languages))
+	for _, lang := range languages {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			return nil, fmt.Errorf("parse language %q: %w", lang, err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return &Bundle{
+		fallback:  fallback,
+		defaults:  Merge(catalogs[DefaultLanguage], catalogs[fallback]),
+		catalogs:  catalogs,
+		languages: languages,
+		matcher:   language.NewMatcher(tags),
+	}, nil
+}
+
+// Default bundle with built-in catalogs.
+func Default() *Bundle {
+	catalogs, err := Load(assets.InsideLocales())
+	if err != nil {
+		panic(err)
+	}
+	b, err := New(DefaultLanguage, catalogs)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Load catalogs from YAML files named by language (ex: en.yaml, pt-BR.yaml) in the root of file system.
+func Load(src fs.FS) (map[string]Messages, error) {
+	entries, err := fs.ReadDir(src, ".")
+	if err != nil {
+		return nil, fmt.Errorf("list catalogs: %w", err)
+	}
+	var catalogs = make(map[string]Messages)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !(ext == ".yaml" || ext == ".yml") {
+			continue
+		}
+		content, err := fs.ReadFile(src, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read %q: %w", entry.Name(), err)
+		}
+		var messages Messages
+		if err := yaml.Unmarshal(content, &messages); err != nil {
+			return nil, fmt.Errorf("parse %q: %w", entry.Name(), err)
+		}
+		lang := Normalize(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		catalogs[lang] = Merge(catalogs[lang], messages)
+	}
+	return catalogs, nil
+}
+
+// Merge catalogs by language: messages from overlay replace or extend base. Base is not modified.
+func Merge(base, overlay Messages) Messages {
+	var ans = make(Messages, len(base)+len(overlay))
+	for k, v := range base {
+		ans[k] = v
+	}
+	for k, v := range overlay {
+		ans[k] = v
+	}
+	return ans
+}
+
+// Normalize language name to canonical form (ex: EN_us -> en-US). Invalid names are only lower-cased.
+func Normalize(lang string) string {
+	lang = strings.ReplaceAll(strings.TrimSpace(lang), "_", "-")
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return strings.ToLower(lang)
+	}
+	return tag.String()
+}
+
+// With returns bundle which also selects additional languages (ex: of form translations). Messages of languages
+// without catalog are taken from fallback language. Invalid and already supported languages are ignored.
+func (b *Bundle) With(languages ...string) *Bundle {
+	var extra []string
+	for _, lang := range languages {
+		lang = Normalize(lang)
+		if slices.Contains(b.languages, lang) || slices.Contains(extra, lang) {
+			continue
+		}
+		if _, err := language.Parse(lang); err != nil {
+			continue
+		}
+		extra = append(extra, lang)
+	}
+	if len(extra) == 0 {
+		return b
+	}
+	slices.Sort(extra)
+
+	ext := *b
+	ext.languages = append(slices.Clone(b.languages), extra...)
+	var tags = make([]language.Tag, 0, len(ext.languages))
+	for _, lang := range ext.languages {
+		tags = append(tags, language.Make(lang))
+	}
+	ext.matcher = language.NewMatcher(tags)
+	return &ext
+}
+
+// Languages supported by bundle, fallback is first.
+func (b *Bundle) Languages() []string {
+	return b.languages
+}
+
+// Localizer for the first supported language from the list. Each item could be language name or Accept-Language
+// header value. Empty items are ignored. If nothing matched - fallback language is used.
+func (b *Bundle) Localizer(languages ...string) *Localizer {
+	var preferred = make([]string, 0, len(languages))
+	for _, lang := range languages {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			preferred = append(preferred, lang)
+		}
+	}
+	lang := b.fallback
+	if len(preferred) > 0 {
+		_, idx := language.MatchStrings(b.matcher, preferred...)
+		lang = b.languages[idx]
+	}
+	return &Localizer{
+		lang:     lang,
+		messages: b.catalogs[lang],
+		fallback: b.defaults,
+	}
+}
+
+// Localizer translates messages to single language. Nil localizer returns keys as-is.
+type Localizer struct {
+	lang     string
+	messages Messages
+	fallback Messages
+}
+
+// Language name.
+func (l *Localizer) Language() string {
+	if l == nil {
+		return DefaultLanguage
+	}
+	return l.lang
+}
+
+// Get message by key and format it with args (if any). Missing messages are taken from fallback language, or key
+// itself is used.
+func (l *Localizer) Get(key string, args ...any) string {
+	text, ok := l.lookup(key)
+	if !ok {
+		text = key
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+func (l *Localizer) lookup(key string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	if v, ok := l.messages[key]; ok {
+		return v, true
+	}
+	v, ok := l.fallback[key]
+	return v, ok
+}
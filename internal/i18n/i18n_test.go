@@ -0,0 +1,95 @@
+package i18n_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/reddec/web-form/internal/i18n"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault(t *testing.T) {
+	bundle := i18n.Default()
+	assert.Equal(t, i18n.DefaultLanguage, bundle.Languages()[0])
+
+	// all built-in catalogs should be complete
+	en := bundle.Localizer("en")
+	for _, lang := range bundle.Languages() {
+		loc := bundle.Localizer(lang)
+		require.Equal(t, lang, loc.Language())
+		for _, key := range []string{"form.send", "error.required", "error.limit-check"} {
+			assert.NotEqual(t, key, loc.Get(key), "%s: %s", lang, key)
+		}
+		if lang != i18n.DefaultLanguage {
+			assert.NotEqual(t, en.Get("form.send"), loc.Get("form.send"), lang)
+		}
+	}
+}
+
+func TestBundle_Localizer(t *testing.T) {
+	bundle, err := i18n.New("en", map[string]i18n.Messages{
+		"en":    {"hello": "Hello, %s", "bye": "Bye"},
+		"de":    {"hello": "Hallo, %s"},
+		"pt-BR": {"hello": "Olá, %s"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en", "de", "pt-BR"}, bundle.Languages())
+
+	cases := []struct {
+		Input []string
+		Lang  string
+	}{
+		{Input: nil, Lang: "en"},
+		{Input: []string{""}, Lang: "en"},
+		{Input: []string{"fr"}, Lang: "en"},
+		{Input: []string{"de-AT"}, Lang: "de"},
+		{Input: []string{"fr-FR,fr;q=0.9,de;q=0.8,en;q=0.7"}, Lang: "de"},
+		{Input: []string{"pt"}, Lang: "pt-BR"},
+		{Input: []string{"", "de"}, Lang: "de"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.Lang, bundle.Localizer(c.Input...).Language(), c.Input)
+	}
+
+	loc := bundle.Localizer("de")
+	assert.Equal(t, "Hallo, Welt", loc.Get("hello", "Welt"))
+	assert.Equal(t, "Bye", loc.Get("bye"))
+	assert.Equal(t, "missing", loc.Get("missing"))
+
+	extended := bundle.With("fr", "de", "fr", "not a language")
+	assert.Equal(t, []string{"en", "de", "pt-BR", "fr"}, extended.Languages())
+	assert.Equal(t, []string{"en", "de", "pt-BR"}, bundle.Languages(), "original bundle is not changed")
+	assert.Equal(t, "fr", extended.Localizer("fr-CA,de;q=0.9").Language())
+	assert.Equal(t, "de", extended.Localizer("de").Language())
+	assert.Equal(t, "Bye", extended.Localizer("fr").Get("bye"))
+	assert.Same(t, bundle, bundle.With("de"))
+
+	var empty *i18n.Localizer
+	assert.Equal(t, "hello", empty.Get("hello"))
+
+	_, err = i18n.New("fr", map[string]i18n.Messages{"en": {}})
+	assert.ErrorIs(t, err, i18n.ErrUnknownLanguage)
+}
+
+func TestLoad(t *testing.T) {
+	catalogs, err := i18n.Load(fstest.MapFS{
+		"EN.yaml":     {Data: []byte("hello: Hello\n")},
+		"pt_br.yml":   {Data: []byte("hello: Olá\n")},
+		"readme.md":   {Data: []byte("ignored")},
+		"nested/x.ya": {Data: []byte("ignored")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]i18n.Messages{
+		"en":    {"hello": "Hello"},
+		"pt-BR": {"hello": "Olá"},
+	}, catalogs)
+
+	merged := i18n.Merge(catalogs["en"], i18n.Messages{"hello": "Hi", "bye": "Bye"})
+	assert.Equal(t, i18n.Messages{"hello": "Hi", "bye": "Bye"}, merged)
+	assert.Equal(t, "Hello", catalogs["en"]["hello"])
+
+	_, err = i18n.Load(fstest.MapFS{"en.yaml": {Data: []byte("- not a map")}})
+	assert.Error(t, err)
+}
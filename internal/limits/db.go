@@ -0,0 +1,91 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/oklog/ulid/v2"
+)
+
+const dbSchema = `
+CREATE TABLE IF NOT EXISTS web_form_limits (
+    form       TEXT   NOT NULL,
+    kind       TEXT   NOT NULL,
+    value      TEXT   NOT NULL,
+    token      TEXT   NOT NULL,
+    created_at BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS web_form_limits_key ON web_form_limits (form, kind, value, created_at);
+`
+
+// NewDB creates counters in database. Table web_form_limits will be created automatically.
+//
+// Acquiring is serialized within the process, and, for Postgres, across instances by advisory lock.
+func NewDB(ctx context.Context, db *sqlx.DB) (*DB, error) {
+	if _, err := db.ExecContext(ctx, dbSchema); err != nil {
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	return &DB{db: db}, nil
+}
+
+type DB struct {
+	lock sync.Mutex
+	db   *sqlx.DB
+}
+
+func (d *DB) Acquire(ctx context.Context, form string, keys []Key) (Release, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if d.db.DriverName() == "pgx" {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "web_form_limits/"+form); err != nil {
+			return nil, fmt.Errorf("lock form counters: %w", err)
+		}
+	}
+
+	now := time.Now()
+	for _, key := range keys {
+		var since int64
+		if key.Window > 0 {
+			since = now.Add(-key.Window).UnixMilli()
+		}
+		var used int
+		err := tx.GetContext(ctx, &used, tx.Rebind(`
+SELECT COUNT(*) FROM web_form_limits WHERE form = ? AND kind = ? AND value = ? AND created_at >= ?`),
+			form, key.Kind, key.Value, since)
+		if err != nil {
+			return nil, fmt.Errorf("count %s submissions: %w", key.Kind, err)
+		}
+		if used >= key.Max {
+			return nil, &ExceededError{Key: key}
+		}
+	}
+
+	token := ulid.Make().String()
+	for _, key := range keys {
+		_, err := tx.ExecContext(ctx, tx.Rebind(`
+INSERT INTO web_form_limits (form, kind, value, token, created_at) VALUES (?, ?, ?, ?, ?)`),
+			form, key.Kind, key.Value, token, now.UnixMilli())
+		if err != nil {
+			return nil, fmt.Errorf("reserve %s slot: %w", key.Kind, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return func(ctx context.Context) error {
+		_, err := d.db.ExecContext(ctx, d.db.Rebind(`DELETE FROM web_form_limits WHERE token = ?`), token)
+		return err
+	}, nil
+}
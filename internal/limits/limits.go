@@ -0,0 +1,67 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/reddec/web-form/internal/codes"
+	"github.com/reddec/web-form/internal/schema"
+)
+
+// Kinds of limits.
+const (
+	KindTotal = "total"
+	KindUser  = "user"
+	KindCode  = "code"
+	KindIP    = "ip"
+)
+
+// Key of counter with maximum allowed value within window.
+type Key struct {
+	Kind   string
+	Value  string // user, digest of code (see codes.Digest) or IP; empty for total
+	Max    int
+	Window time.Duration // sliding time window, zero means forever
+}
+
+// ExceededError returned if one of limits reached.
+type ExceededError struct {
+	Key Key
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s limit (%d) exceeded", e.Key.Kind, e.Key.Max)
+}
+
+// Release slots acquired by Store.Acquire. Should be called if submission was not stored.
+type Release func(ctx context.Context) error
+
+// Store of submission counters.
+type Store interface {
+	// Acquire atomically checks that number of submissions for each key within key's window is bellow limit and,
+	// if so, reserves one slot for each key. Returns *ExceededError if any limit reached.
+	Acquire(ctx context.Context, form string, keys []Key) (Release, error)
+}
+
+// Keys for all defined limits. Per-user, per-code and per-IP limits are skipped for empty values (ex: anonymous user).
+// Limits without own window use common window of limits. Access codes are never stored as-is, only their digests.
+func Keys(limits *schema.Limits, user, code, ip string) []Key {
+	var ans []Key
+	add := func(kind, value string, limit schema.Limit) {
+		if limit.Count > 0 {
+			ans = append(ans, Key{Kind: kind, Value: value, Max: limit.Count, Window: limit.WindowOr(limits.Window)})
+		}
+	}
+	add(KindTotal, "", limits.Total)
+	if user != "" {
+		add(KindUser, user, limits.PerUser)
+	}
+	if code != "" {
+		add(KindCode, codes.Digest(code), limits.PerCode)
+	}
+	if ip != "" {
+		add(KindIP, ip, limits.PerIP)
+	}
+	return ans
+}
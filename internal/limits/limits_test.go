@@ -0,0 +1,93 @@
+package limits_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/codes"
+	"github.com/reddec/web-form/internal/limits"
+	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestKeys(t *testing.T) {
+	keys := limits.Keys(&schema.Limits{
+		Total:   schema.Limit{Count: 10},
+		PerUser: schema.Limit{Count: 1},
+		PerCode: schema.Limit{Count: 2},
+		PerIP:   schema.Limit{Count: 3, Window: time.Hour},
+		Window:  24 * time.Hour,
+	}, "", "secret", "127.0.0.1")
+	assert.Equal(t, []limits.Key{
+		{Kind: limits.KindTotal, Max: 10, Window: 24 * time.Hour},
+		{Kind: limits.KindCode, Value: codes.Digest("secret"), Max: 2, Window: 24 * time.Hour},
+		{Kind: limits.KindIP, Value: "127.0.0.1", Max: 3, Window: time.Hour},
+	}, keys)
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, limits.NewMemory())
+}
+
+func TestDB_sqlite(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	s, err := storage.NewDB(ctx, "sqlite", "file::memory:")
+	require.NoError(t, err)
+	defer s.Close()
+
+	store, err := limits.NewDB(ctx, s.DB())
+	require.NoError(t, err)
+	testStore(t, store)
+}
+
+func testStore(t *testing.T, store limits.Store) {
+	ctx := context.Background()
+	total := limits.Key{Kind: limits.KindTotal, Max: 2}
+	alice := limits.Key{Kind: limits.KindUser, Value: "alice", Max: 1}
+	bob := limits.Key{Kind: limits.KindUser, Value: "bob", Max: 1}
+
+	_, err := store.Acquire(ctx, "form", []limits.Key{total, alice})
+	require.NoError(t, err)
+
+	// per-user limit reached, total should not be consumed
+	_, err = store.Acquire(ctx, "form", []limits.Key{total, alice})
+	var exceeded *limits.ExceededError
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, alice, exceeded.Key)
+
+	// other forms are independent
+	_, err = store.Acquire(ctx, "other", []limits.Key{total, alice})
+	require.NoError(t, err)
+
+	// released slot can be used again
+	release, err := store.Acquire(ctx, "form", []limits.Key{total, bob})
+	require.NoError(t, err)
+	require.NoError(t, release(ctx))
+	_, err = store.Acquire(ctx, "form", []limits.Key{total, bob})
+	require.NoError(t, err)
+
+	// total reached
+	_, err = store.Acquire(ctx, "form", []limits.Key{total})
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, total, exceeded.Key)
+
+	// old submissions are outside of window, windows are per key
+	time.Sleep(10 * time.Millisecond)
+	recent := limits.Key{Kind: limits.KindUser, Value: "carol", Max: 1, Window: 5 * time.Millisecond}
+	_, err = store.Acquire(ctx, "other", []limits.Key{recent})
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = store.Acquire(ctx, "other", []limits.Key{recent})
+	require.NoError(t, err)
+	_, err = store.Acquire(ctx, "form", []limits.Key{total, recent})
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, total, exceeded.Key)
+}
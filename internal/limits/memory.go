@@ -0,0 +1,73 @@
+package limits
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewMemory creates in-memory counters. Counters are lost on restart.
+func NewMemory() *Memory {
+	return &Memory{usage: make(map[string][]time.Time)}
+}
+
+type Memory struct {
+	lock  sync.Mutex
+	usage map[string][]time.Time // key -> time of submissions
+}
+
+func (m *Memory) Acquire(_ context.Context, form string, keys []Key) (Release, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		id := memoryKey(form, key)
+		used := m.usage[id]
+		if key.Window > 0 {
+			used = dropBefore(used, now.Add(-key.Window))
+			m.usage[id] = used
+		}
+		if len(used) >= key.Max {
+			return nil, &ExceededError{Key: key}
+		}
+	}
+
+	for _, key := range keys {
+		id := memoryKey(form, key)
+		m.usage[id] = append(m.usage[id], now)
+	}
+
+	return func(context.Context) error {
+		m.release(form, keys, now)
+		return nil
+	}, nil
+}
+
+func (m *Memory) release(form string, keys []Key, at time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, key := range keys {
+		id := memoryKey(form, key)
+		used := m.usage[id]
+		for i := len(used) - 1; i >= 0; i-- {
+			if used[i].Equal(at) {
+				m.usage[id] = append(used[:i], used[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func memoryKey(form string, key Key) string {
+	return form + "\x00" + key.Kind + "\x00" + key.Value
+}
+
+// dropBefore removes sorted time points before the threshold.
+func dropBefore(points []time.Time, threshold time.Time) []time.Time {
+	var i int
+	for i < len(points) && points[i].Before(threshold) {
+		i++
+	}
+	return points[i:]
+}
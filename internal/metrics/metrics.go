@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "webform"
+
+// Submission results.
+const (
+	ResultSuccess          = "success"
+	ResultValidationFailed = "validation_failed"
+	ResultStoreFailed      = "store_failed"
+	ResultHookFailed       = "hook_failed"
+	ResultLimitReached     = "limit_reached"
+	ResultCodeRejected     = "code_rejected"
+)
+
+// Delivery results.
+const (
+	DeliverySuccess = "success"
+	DeliveryFailure = "failure"
+)
+
+//nolint:gochecknoglobals
+var (
+	FormViews = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "form_views_total",
+		Help:      "Number of rendered forms (without submission)",
+	}, []string{"form"})
+
+	Submissions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "submissions_total",
+		Help:      "Number of form submissions by result",
+	}, []string{"form", "result"})
+
+	StoreDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_duration_seconds",
+		Help:      "Latency of saving submission to the storage",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"form"})
+
+	CaptchaFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "captcha_failures_total",
+		Help:      "Number of failed captcha validations",
+	}, []string{"form"})
+
+	SpamDetected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "spam_detected_total",
+		Help:      "Number of submissions rejected by spam checks",
+	}, []string{"form", "check"})
+
+	XSRFFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "xsrf_failures_total",
+		Help:      "Number of failed XSRF validations",
+	}, []string{"form"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Number of requests rejected by rate limits",
+	}, []string{"form", "scope"})
+
+	DeliveryAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "delivery_attempts_total",
+		Help:      "Number of notification delivery attempts by result",
+	}, []string{"kind", "result"})
+
+	DeliveryRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "delivery_retries_total",
+		Help:      "Number of notification delivery retries",
+	}, []string{"kind"})
+
+	DeliveryFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "delivery_failures_total",
+		Help:      "Number of notifications which were not delivered after all attempts",
+	}, []string{"kind"})
+
+	DeliveryDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "delivery_dropped_total",
+		Help:      "Number of notifications dropped during shutdown",
+	}, []string{"kind"})
+)
+
+// Queue exposes current depth and capacity of notification queue.
+type Queue interface {
+	Pending() int
+	Capacity() int
+}
+
+// RegisterQueue registers gauges for queue depth and capacity.
+func RegisterQueue(kind string, queue Queue) {
+	labels := prometheus.Labels{"kind": kind}
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "queue_depth",
+			Help:        "Number of pending notifications in internal queue",
+			ConstLabels: labels,
+		}, func() float64 {
+			return float64(queue.Pending())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "queue_capacity",
+			Help:        "Maximum number of pending notifications in internal queue",
+			ConstLabels: labels,
+		}, func() float64 {
+			return float64(queue.Capacity())
+		}),
+	)
+}
@@ -2,20 +2,32 @@ package amqp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rabbitmq/amqp091-go"
+	"github.com/reddec/web-form/internal/deliveries"
+	"github.com/reddec/web-form/internal/metrics"
 	"github.com/reddec/web-form/internal/notifications"
 	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var ErrClosed = errors.New("publisher closed")
+
 const (
 	defaultTimeout  = 10 * time.Second
 	defaultRetries  = 3
 	defaultInterval = 15 * time.Second
+	dropTimeout     = 5 * time.Second // timeout to record dropped message
 )
 
 // copied from amp091 defaults.
@@ -24,13 +36,47 @@ const (
 	defaultLocale    = "en_US"
 )
 
-func New(url string, buffer int) *AMQP {
-	return &AMQP{url: url, tasks: make(chan task, buffer)}
+type Option func(amqp *AMQP)
+
+// WithLog sets log for delivery attempts. Default is no log.
+func WithLog(log deliveries.Log) Option {
+	return func(amqp *AMQP) {
+		amqp.log = log
+	}
+}
+
+func New(url string, buffer int, options ...Option) *AMQP {
+	a := &AMQP{url: url, tasks: make(chan task, buffer), log: deliveries.Nop{}}
+	for _, opt := range options {
+		opt(a)
+	}
+	return a
 }
 
 type AMQP struct {
-	url   string
-	tasks chan task
+	url     string
+	tasks   chan task
+	log     deliveries.Log
+	dropped atomic.Int64
+
+	closeLock sync.RWMutex
+	closed    bool
+
+	stateLock sync.RWMutex
+	connErr   error // last connection error
+}
+
+// Ping returns last broker connection error (if any). Since connections are lazy, it doesn't dial broker.
+func (amqp *AMQP) Ping(context.Context) error {
+	amqp.stateLock.RLock()
+	defer amqp.stateLock.RUnlock()
+	return amqp.connErr
+}
+
+func (amqp *AMQP) setConnectionState(err error) {
+	amqp.stateLock.Lock()
+	defer amqp.stateLock.Unlock()
+	amqp.connErr = err
 }
 
 //nolint:cyclop
@@ -79,17 +125,68 @@ func (amqp *AMQP) Create(definition schema.AMQP) notifications.Notification {
 			correlationID: correlationID,
 			messageID:     messageID,
 			payload:       payload,
+			submission:    event.Submission,
+			form:          event.FormName(),
+			trace:         trace.SpanContextFromContext(ctx),
 		}
 
+		return amqp.enqueue(ctx, t)
+	})
+}
+
+func (amqp *AMQP) enqueue(ctx context.Context, t task) error {
+	amqp.closeLock.RLock()
+	defer amqp.closeLock.RUnlock()
+	if amqp.closed {
+		return ErrClosed
+	}
+	select {
+	case amqp.tasks <- t:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting new messages. Already queued messages will be published by Run.
+func (amqp *AMQP) Close() {
+	amqp.closeLock.Lock()
+	defer amqp.closeLock.Unlock()
+	if !amqp.closed {
+		amqp.closed = true
+		close(amqp.tasks)
+	}
+}
+
+// Discard removes all unpublished messages from the queue, records them as dropped,
+// and returns total number of dropped messages including interrupted retries.
+// Should be called after all Run finished.
+func (amqp *AMQP) Discard(ctx context.Context) int {
+	for {
 		select {
-		case amqp.tasks <- t:
-			return nil
-		case <-ctx.Done():
-			return ctx.Err()
+		case t, ok := <-amqp.tasks:
+			if !ok {
+				return int(amqp.dropped.Load())
+			}
+			amqp.drop(ctx, t, 1)
+		default:
+			return int(amqp.dropped.Load())
 		}
-	})
+	}
+}
+
+// Pending returns number of queued messages.
+func (amqp *AMQP) Pending() int {
+	return len(amqp.tasks)
 }
 
+// Capacity returns maximum number of queued messages.
+func (amqp *AMQP) Capacity() int {
+	return cap(amqp.tasks)
+}
+
+// Run publishes messages until context is canceled or publisher is closed and queue is empty.
+// Pending retries are interrupted only by context.
 func (amqp *AMQP) Run(ctx context.Context) {
 	w := &worker{url: amqp.url}
 	defer w.close()
@@ -100,18 +197,26 @@ func (amqp *AMQP) Run(ctx context.Context) {
 			if !ok {
 				return
 			}
-			amqp.sendTask(ctx, w, t)
+			if attempt, interrupted := amqp.sendTask(ctx, w, t); interrupted {
+				dropCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dropTimeout)
+				amqp.drop(dropCtx, t, attempt)
+				cancel()
+			}
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (amqp *AMQP) sendTask(ctx context.Context, w *worker, t task) {
+// sendTask publishes message with retries. Returns number of the next attempt and true if retries were interrupted by context.
+func (amqp *AMQP) sendTask(ctx context.Context, w *worker, t task) (int, bool) {
 	var attempt int
 	logger := slog.With("routing-key", t.key, "exchange", t.definition.Exchange, "message-id", t.messageID)
 	for {
-		if err := amqp.trySendTask(ctx, w, t); err != nil {
+		started := time.Now()
+		err := amqp.trySendTask(ctx, w, t)
+		amqp.record(ctx, t, attempt+1, time.Since(started), err)
+		if err != nil {
 			w.close() // reset state on error
 			logger.Warn("failed publish AMQP message", "error", err, "attempt", attempt+1, "retries", t.definition.Retry, "retry-after", t.definition.Interval)
 		} else {
@@ -120,23 +225,81 @@ func (amqp *AMQP) sendTask(ctx context.Context, w *worker, t task) {
 		}
 
 		if attempt >= t.definition.Retry {
+			metrics.DeliveryFailures.WithLabelValues(deliveries.KindAMQP).Inc()
 			break
 		}
 		select {
 		case <-time.After(t.definition.Timeout):
 		case <-ctx.Done():
 			logger.Info("publishing stopped due to global context stop")
-			return
+			return attempt + 2, true
 		}
 		attempt++
+		metrics.DeliveryRetries.WithLabelValues(deliveries.KindAMQP).Inc()
+	}
+	return 0, false
+}
+
+func (amqp *AMQP) drop(ctx context.Context, t task, attempt int) {
+	amqp.dropped.Add(1)
+	metrics.DeliveryDropped.WithLabelValues(deliveries.KindAMQP).Inc()
+	slog.Warn("AMQP message dropped", "routing-key", t.key, "exchange", t.definition.Exchange, "submission", t.submission, "attempt", attempt)
+	err := amqp.log.Record(ctx, deliveries.Attempt{
+		Submission:  t.submission,
+		Form:        t.form,
+		Kind:        deliveries.KindAMQP,
+		Destination: t.definition.Exchange + "/" + t.key,
+		Attempt:     attempt,
+		Error:       deliveries.ErrDropped.Error(),
+		At:          time.Now(),
+	})
+	if err != nil {
+		slog.Error("failed record dropped AMQP message", "routing-key", t.key, "exchange", t.definition.Exchange, "error", err)
+	}
+}
+
+func (amqp *AMQP) record(ctx context.Context, t task, attempt int, latency time.Duration, err error) {
+	var errMessage string
+	var result = metrics.DeliverySuccess
+	if err != nil {
+		errMessage = err.Error()
+		result = metrics.DeliveryFailure
+	}
+	metrics.DeliveryAttempts.WithLabelValues(deliveries.KindAMQP, result).Inc()
+	recordErr := amqp.log.Record(ctx, deliveries.Attempt{
+		Submission:  t.submission,
+		Form:        t.form,
+		Kind:        deliveries.KindAMQP,
+		Destination: t.definition.Exchange + "/" + t.key,
+		Attempt:     attempt,
+		Error:       errMessage,
+		Latency:     latency,
+		At:          time.Now(),
+	})
+	if recordErr != nil {
+		slog.Error("failed record AMQP delivery attempt", "routing-key", t.key, "exchange", t.definition.Exchange, "error", recordErr)
 	}
 }
 
-func (amqp *AMQP) trySendTask(global context.Context, w *worker, t task) error {
-	ctx, cancel := context.WithTimeout(global, t.definition.Timeout)
+func (amqp *AMQP) trySendTask(global context.Context, w *worker, t task) (err error) {
+	ctx, span := tracing.Start(trace.ContextWithSpanContext(global, t.trace), "amqp.Publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("submission", t.submission),
+			attribute.String("messaging.destination.name", t.definition.Exchange),
+			attribute.String("messaging.rabbitmq.destination.routing_key", t.key),
+		),
+	)
+	defer func() {
+		tracing.Fail(span, err)
+		span.End()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, t.definition.Timeout)
 	defer cancel()
 
 	ch, err := w.getChannel(ctx)
+	amqp.setConnectionState(err)
 	if err != nil {
 		return fmt.Errorf("get channel: %w", err)
 	}
@@ -145,6 +308,7 @@ func (amqp *AMQP) trySendTask(global context.Context, w *worker, t task) error {
 	for k, v := range t.definition.Headers {
 		headers[k] = v
 	}
+	tracing.Inject(ctx, headersCarrier(headers))
 
 	return ch.PublishWithContext(ctx, t.definition.Exchange, t.key, false, false, amqp091.Publishing{
 		MessageId:     t.messageID,
@@ -220,4 +384,27 @@ type task struct {
 	correlationID string
 	messageID     string
 	payload       []byte
+	submission    string
+	form          string
+	trace         trace.SpanContext // origin of the task
+}
+
+// headersCarrier adapts AMQP headers to trace context propagation.
+type headersCarrier amqp091.Table
+
+func (hc headersCarrier) Get(key string) string {
+	v, _ := hc[key].(string)
+	return v
+}
+
+func (hc headersCarrier) Set(key string, value string) {
+	hc[key] = value
+}
+
+func (hc headersCarrier) Keys() []string {
+	var ans = make([]string, 0, len(hc))
+	for k := range hc {
+		ans = append(ans, k)
+	}
+	return ans
 }
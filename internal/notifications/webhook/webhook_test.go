@@ -9,10 +9,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/reddec/web-form/internal/deliveries"
 	"github.com/reddec/web-form/internal/notifications/webhook"
 	"github.com/reddec/web-form/internal/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func TestDispatcher_Dispatch(t *testing.T) {
@@ -97,6 +101,147 @@ func TestDispatcher_Dispatch(t *testing.T) {
 	})
 }
 
+func TestDispatcher_log(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	log := deliveries.NewMemory(10)
+	dispatcher := webhook.New(1, webhook.WithLog(log))
+	go dispatcher.Run(ctx)
+
+	server, requests := createTestServer(t)
+	defer server.Close()
+
+	notify := dispatcher.Create(schema.Webhook{
+		URL: server.URL,
+	})
+
+	err := notify.Dispatch(ctx, schema.NotifyContext{
+		Form:       &schema.Form{Name: "pizza"},
+		Result:     map[string]any{"Name": t.Name()},
+		Submission: "abc",
+	})
+	require.NoError(t, err)
+	requireReceive(t, ctx, requests)
+
+	require.Eventually(t, func() bool {
+		list, _ := log.List(ctx, "abc")
+		return len(list) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	list, err := log.List(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "pizza", list[0].Form)
+	assert.Equal(t, deliveries.KindWebhook, list[0].Kind)
+	assert.Equal(t, server.URL, list[0].Destination)
+	assert.Equal(t, 1, list[0].Attempt)
+	assert.Equal(t, http.StatusOK, list[0].Code)
+	assert.True(t, list[0].Delivered())
+
+	// unlogged webhooks are delivered, but not recorded
+	err = dispatcher.CreateUnlogged(schema.Webhook{URL: server.URL}).Dispatch(ctx, schema.NotifyContext{
+		Result: map[string]any{"Name": t.Name()},
+	})
+	require.NoError(t, err)
+	requireReceive(t, ctx, requests)
+	time.Sleep(50 * time.Millisecond)
+	list, err = log.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestDispatcher_tracing(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	provider := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dispatcher := webhook.New(1)
+	go dispatcher.Run(ctx)
+
+	server, requests := createTestServer(t)
+	defer server.Close()
+
+	notify := dispatcher.Create(schema.Webhook{
+		URL: server.URL,
+	})
+
+	spanCtx, span := provider.Tracer("test").Start(ctx, "submit")
+	err := notify.Dispatch(spanCtx, schema.NotifyContext{
+		Result: map[string]any{"Name": t.Name()},
+	})
+	span.End()
+	require.NoError(t, err)
+
+	req := requireReceive(t, ctx, requests)
+	assert.Contains(t, req.Header.Get("Traceparent"), span.SpanContext().TraceID().String())
+}
+
+func TestDispatcher_Close(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	t.Run("drains queue", func(t *testing.T) {
+		dispatcher := webhook.New(2)
+		server, requests := createTestServer(t)
+		defer server.Close()
+
+		notify := dispatcher.Create(schema.Webhook{URL: server.URL})
+		require.NoError(t, notify.Dispatch(ctx, schema.NotifyContext{Result: map[string]any{"Name": "first"}}))
+		dispatcher.Close()
+
+		// queued webhook should be delivered, new ones rejected
+		assert.ErrorIs(t, notify.Dispatch(ctx, schema.NotifyContext{}), webhook.ErrClosed)
+		go dispatcher.Run(ctx)
+		requireReceive(t, ctx, requests)
+		assert.Zero(t, dispatcher.Discard(ctx))
+	})
+
+	t.Run("reports dropped", func(t *testing.T) {
+		log := deliveries.NewMemory(10)
+		dispatcher := webhook.New(2, webhook.WithLog(log))
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		notify := dispatcher.Create(schema.Webhook{URL: server.URL})
+		require.NoError(t, notify.Dispatch(ctx, schema.NotifyContext{Submission: "retrying"}))
+
+		workCtx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			dispatcher.Run(workCtx)
+		}()
+		require.Eventually(t, func() bool {
+			list, _ := log.List(ctx, "retrying")
+			return len(list) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// one more webhook left in queue after workers stopped
+		stop()
+		<-done
+		require.NoError(t, notify.Dispatch(ctx, schema.NotifyContext{Submission: "queued"}))
+		dispatcher.Close()
+		assert.Equal(t, 2, dispatcher.Discard(ctx))
+
+		list, err := log.List(ctx, "retrying")
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, deliveries.ErrDropped.Error(), list[1].Error)
+		assert.Equal(t, 2, list[1].Attempt)
+
+		list, err = log.List(ctx, "queued")
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, deliveries.ErrDropped.Error(), list[0].Error)
+	})
+}
+
 func createTestServer(t *testing.T) (*httptest.Server, <-chan *http.Request) {
 	var arrived = make(chan *http.Request, 1)
 
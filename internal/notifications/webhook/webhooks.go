@@ -10,14 +10,23 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/reddec/web-form/internal/deliveries"
+	"github.com/reddec/web-form/internal/metrics"
 	"github.com/reddec/web-form/internal/notifications"
 	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	ErrNonSuccessCode = errors.New("non-2xx response code")
+	ErrClosed         = errors.New("dispatcher closed")
 )
 
 const (
@@ -25,17 +34,54 @@ const (
 	defaultRetries  = 3
 	defaultInterval = 15 * time.Second
 	defaultMethod   = http.MethodPost
+	dropTimeout     = 5 * time.Second // timeout to record dropped webhook
 )
 
-func New(buffer int) *Dispatcher {
-	return &Dispatcher{tasks: make(chan webhookTask, buffer)}
+type Option func(dispatcher *Dispatcher)
+
+// WithClient sets HTTP client for webhooks. Default is http.DefaultClient.
+func WithClient(client *http.Client) Option {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.client = client
+	}
+}
+
+// WithLog sets log for delivery attempts. Default is no log.
+func WithLog(log deliveries.Log) Option {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.log = log
+	}
+}
+
+func New(buffer int, options ...Option) *Dispatcher {
+	d := &Dispatcher{tasks: make(chan webhookTask, buffer), log: deliveries.Nop{}, client: http.DefaultClient}
+	for _, opt := range options {
+		opt(d)
+	}
+	return d
 }
 
 type Dispatcher struct {
-	tasks chan webhookTask
+	tasks   chan webhookTask
+	log     deliveries.Log
+	client  *http.Client
+	dropped atomic.Int64
+
+	closeLock sync.RWMutex
+	closed    bool
 }
 
 func (wd *Dispatcher) Create(webhook schema.Webhook) notifications.Notification {
+	return wd.create(webhook, wd.log)
+}
+
+// CreateUnlogged creates webhook which delivery attempts are not recorded in delivery log, for example for events
+// which don't belong to submissions. Queue and workers are shared with other webhooks.
+func (wd *Dispatcher) CreateUnlogged(webhook schema.Webhook) notifications.Notification {
+	return wd.create(webhook, deliveries.Nop{})
+}
+
+func (wd *Dispatcher) create(webhook schema.Webhook, log deliveries.Log) notifications.Notification {
 	if webhook.Timeout <= 0 {
 		webhook.Timeout = defaultTimeout
 	}
@@ -63,10 +109,20 @@ func (wd *Dispatcher) Create(webhook schema.Webhook) notifications.Notification
 		if err != nil {
 			return fmt.Errorf("render webhook: %w", err)
 		}
-		return wd.enqueue(ctx, webhook, payload)
+		return wd.enqueue(ctx, webhookTask{
+			webhook:    webhook,
+			payload:    payload,
+			submission: event.Submission,
+			form:       event.FormName(),
+			log:        log,
+			client:     wd.client,
+			trace:      trace.SpanContextFromContext(ctx),
+		})
 	})
 }
 
+// Run processes webhooks until context is canceled or dispatcher is closed and queue is empty.
+// Pending retries are interrupted only by context.
 func (wd *Dispatcher) Run(ctx context.Context) {
 	var wg sync.WaitGroup
 	defer wg.Wait()
@@ -79,7 +135,11 @@ func (wd *Dispatcher) Run(ctx context.Context) {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				task.Send(ctx)
+				if attempt, interrupted := task.Send(ctx); interrupted {
+					dropCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dropTimeout)
+					defer cancel()
+					wd.drop(dropCtx, &task, attempt)
+				}
 			}()
 		case <-ctx.Done():
 			return
@@ -87,29 +147,102 @@ func (wd *Dispatcher) Run(ctx context.Context) {
 	}
 }
 
-func (wd *Dispatcher) enqueue(ctx context.Context, webhook schema.Webhook, payload []byte) error {
+// Close stops accepting new webhooks. Already queued webhooks will be processed by Run.
+func (wd *Dispatcher) Close() {
+	wd.closeLock.Lock()
+	defer wd.closeLock.Unlock()
+	if !wd.closed {
+		wd.closed = true
+		close(wd.tasks)
+	}
+}
+
+// Discard removes all unprocessed webhooks from the queue, records them as dropped,
+// and returns total number of dropped webhooks including interrupted retries.
+// Should be called after Run finished.
+func (wd *Dispatcher) Discard(ctx context.Context) int {
+	for {
+		select {
+		case task, ok := <-wd.tasks:
+			if !ok {
+				return int(wd.dropped.Load())
+			}
+			wd.drop(ctx, &task, 1)
+		default:
+			return int(wd.dropped.Load())
+		}
+	}
+}
+
+// Pending returns number of queued webhooks.
+func (wd *Dispatcher) Pending() int {
+	return len(wd.tasks)
+}
+
+// Capacity returns maximum number of queued webhooks.
+func (wd *Dispatcher) Capacity() int {
+	return cap(wd.tasks)
+}
+
+func (wd *Dispatcher) enqueue(ctx context.Context, task webhookTask) error {
+	wd.closeLock.RLock()
+	defer wd.closeLock.RUnlock()
+	if wd.closed {
+		return ErrClosed
+	}
 	select {
-	case wd.tasks <- webhookTask{webhook: webhook, payload: payload}:
+	case wd.tasks <- task:
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 	return nil
 }
 
+func (wd *Dispatcher) drop(ctx context.Context, task *webhookTask, attempt int) {
+	wd.dropped.Add(1)
+	metrics.DeliveryDropped.WithLabelValues(deliveries.KindWebhook).Inc()
+	destination := task.webhook.URL
+	if u, err := url.Parse(task.webhook.URL); err == nil {
+		destination = u.Redacted()
+	}
+	slog.Warn("webhook dropped", "url", destination, "submission", task.submission, "attempt", attempt)
+	err := task.log.Record(ctx, deliveries.Attempt{
+		Submission:  task.submission,
+		Form:        task.form,
+		Kind:        deliveries.KindWebhook,
+		Destination: destination,
+		Attempt:     attempt,
+		Error:       deliveries.ErrDropped.Error(),
+		At:          time.Now(),
+	})
+	if err != nil {
+		slog.Error("failed record dropped webhook", "url", destination, "error", err)
+	}
+}
+
 type webhookTask struct {
-	webhook schema.Webhook
-	payload []byte
+	webhook    schema.Webhook
+	payload    []byte
+	submission string
+	form       string
+	log        deliveries.Log
+	client     *http.Client
+	trace      trace.SpanContext // origin of the task
 }
 
-func (wt *webhookTask) Send(global context.Context) {
+// Send webhook with retries. Returns number of the next attempt and true if retries were interrupted by global context.
+func (wt *webhookTask) Send(global context.Context) (int, bool) {
 	u, err := url.Parse(wt.webhook.URL)
 	if err != nil {
 		slog.Error("webhook url invalid - skipping", "url", wt.webhook.URL, "error", err)
-		return
+		return 0, false
 	}
 	var attempt int
 	for {
-		if err := wt.trySend(global); err != nil {
+		started := time.Now()
+		code, err := wt.trySend(global)
+		wt.record(global, u, attempt+1, code, time.Since(started), err)
+		if err != nil {
 			slog.Warn("failed deliver webhook", "url", u.Redacted(), "error", err, "attempt", attempt+1, "retries", wt.webhook.Retry, "retry-after", wt.webhook.Interval)
 		} else {
 			slog.Info("webhook delivered", "url", u.Redacted(), "attempt", attempt+1, "retries", wt.webhook.Retry)
@@ -117,41 +250,79 @@ func (wt *webhookTask) Send(global context.Context) {
 		}
 
 		if attempt >= wt.webhook.Retry {
+			metrics.DeliveryFailures.WithLabelValues(deliveries.KindWebhook).Inc()
 			break
 		}
 		select {
 		case <-time.After(wt.webhook.Timeout):
 		case <-global.Done():
 			slog.Info("webhook retry stopped due to global context stop")
-			return
+			return attempt + 2, true
 		}
 		attempt++
+		metrics.DeliveryRetries.WithLabelValues(deliveries.KindWebhook).Inc()
 	}
+	return 0, false
 }
 
-func (wt *webhookTask) trySend(global context.Context) error {
-	ctx, cancel := context.WithTimeout(global, wt.webhook.Timeout)
+func (wt *webhookTask) record(ctx context.Context, u *url.URL, attempt int, code int, latency time.Duration, err error) {
+	var errMessage string
+	var result = metrics.DeliverySuccess
+	if err != nil {
+		errMessage = err.Error()
+		result = metrics.DeliveryFailure
+	}
+	metrics.DeliveryAttempts.WithLabelValues(deliveries.KindWebhook, result).Inc()
+	recordErr := wt.log.Record(ctx, deliveries.Attempt{
+		Submission:  wt.submission,
+		Form:        wt.form,
+		Kind:        deliveries.KindWebhook,
+		Destination: u.Redacted(),
+		Attempt:     attempt,
+		Code:        code,
+		Error:       errMessage,
+		Latency:     latency,
+		At:          time.Now(),
+	})
+	if recordErr != nil {
+		slog.Error("failed record webhook delivery attempt", "url", u.Redacted(), "error", recordErr)
+	}
+}
+
+func (wt *webhookTask) trySend(global context.Context) (code int, err error) {
+	ctx, span := tracing.Start(trace.ContextWithSpanContext(global, wt.trace), "webhook.Deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("submission", wt.submission), attribute.String("http.method", wt.webhook.Method)),
+	)
+	defer func() {
+		span.SetAttributes(attribute.Int("http.status_code", code))
+		tracing.Fail(span, err)
+		span.End()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, wt.webhook.Timeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, wt.webhook.Method, wt.webhook.URL, bytes.NewReader(wt.payload))
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return 0, fmt.Errorf("create request: %w", err)
 	}
 
 	for k, v := range wt.webhook.Headers {
 		req.Header.Set(k, v)
 	}
+	tracing.Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := wt.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("execute request: %w", err)
+		return 0, fmt.Errorf("execute request: %w", err)
 	}
 	defer res.Body.Close()
 
 	_, _ = io.Copy(io.Discard, res.Body) // drain content to keep connection healthy
 
 	if res.StatusCode/100 != 2 {
-		return fmt.Errorf("%w: %d", ErrNonSuccessCode, res.StatusCode)
+		return res.StatusCode, fmt.Errorf("%w: %d", ErrNonSuccessCode, res.StatusCode)
 	}
-	return nil
+	return res.StatusCode, nil
 }
@@ -0,0 +1,244 @@
+// Package oidcauth protects pages by one or more OIDC providers. If more than one provider is configured, user
+// chooses provider on selection page.
+package oidcauth
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/reddec/web-form/internal/assets"
+	"github.com/reddec/web-form/internal/claims"
+	"github.com/reddec/web-form/internal/i18n"
+	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/web"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-chi/chi/v5"
+	oidclogin "github.com/reddec/oidc-login"
+)
+
+const (
+	Prefix          = oidclogin.Prefix // where callbacks and provider chooser are mounted
+	sessionProvider = "oidc-provider"
+	sessionRedirect = "redirect-to"
+)
+
+type Option func(auth *Auth)
+
+// WithLocales sets message catalogs of the provider chooser page, built-in by default.
+func WithLocales(locales *i18n.Bundle) Option {
+	return func(auth *Auth) {
+		auth.locales = locales
+	}
+}
+
+// WithView replaces built-in provider chooser page.
+func WithView(view *template.Template) Option {
+	return func(auth *Auth) {
+		auth.view = view
+	}
+}
+
+// New authentication by OIDC providers. Provider state is kept in separate sessions (cookie per provider) in the same
+// store as sessions, which are also used for selected provider and should be loaded by [scs.SessionManager.LoadAndSave].
+// Server URL is optional public URL used for callbacks. Auth should be mounted to Prefix.
+func New(ctx context.Context, sessions *scs.SessionManager, serverURL string, providers []Provider, options ...Option) (*Auth, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("%w: no providers", ErrInvalidProvider)
+	}
+	a := &Auth{
+		sessions: sessions,
+		byName:   make(map[string]*provider, len(providers)),
+		mux:      chi.NewMux(),
+	}
+	for _, opt := range options {
+		opt(a)
+	}
+	if a.locales == nil {
+		a.locales = i18n.Default()
+	}
+	if a.view == nil {
+		a.view = template.Must(assets.ParseView(assets.InsideViews(), "providers.gohtml"))
+	}
+	for _, cfg := range providers {
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := a.byName[cfg.Name]; exists {
+			return nil, fmt.Errorf("%w: duplicated name %q", ErrInvalidProvider, cfg.Name)
+		}
+		p, err := a.newProvider(ctx, serverURL, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("create provider %q: %w", cfg.Name, err)
+		}
+		a.providers = append(a.providers, p)
+		a.byName[cfg.Name] = p
+
+		route := "/" + strings.TrimPrefix(p.prefix, Prefix)
+		a.mux.Handle(route+"callback", p.auth)
+		a.mux.Handle(route+"logout", a.logout(p))
+	}
+	a.mux.Get("/select", a.showProviders)
+	a.mux.Get("/select/{provider}", a.selectProvider)
+	return a, nil
+}
+
+type Auth struct {
+	sessions  *scs.SessionManager
+	providers []*provider
+	byName    map[string]*provider
+	view      *template.Template
+	locales   *i18n.Bundle
+	mux       *chi.Mux
+}
+
+type provider struct {
+	name    string
+	title   string
+	prefix  string
+	auth    *oidclogin.OIDC
+	mapping *claims.Mapping
+}
+
+func (a *Auth) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	a.mux.ServeHTTP(writer, request)
+}
+
+// Secure handler: requests are checked by selected provider. If provider is not selected yet, user is redirected to
+// provider chooser. Credentials, tagged by provider name, are added to request context.
+func (a *Auth) Secure(next http.Handler) http.Handler {
+	var secured = make(map[string]http.Handler, len(a.providers))
+	for _, p := range a.providers {
+		secured[p.name] = p.auth.Secure(p.withCredentials(next))
+	}
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		p := a.selected(request)
+		if p == nil {
+			http.Redirect(writer, request, Prefix+"select?redirect="+url.QueryEscape(request.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		secured[p.name].ServeHTTP(writer, request)
+	})
+}
+
+func (a *Auth) selected(request *http.Request) *provider {
+	if len(a.providers) == 1 {
+		return a.providers[0]
+	}
+	return a.byName[a.sessions.GetString(request.Context(), sessionProvider)]
+}
+
+func (a *Auth) showProviders(writer http.ResponseWriter, request *http.Request) {
+	type option struct {
+		Name  string
+		Title string
+	}
+	var options = make([]option, 0, len(a.providers))
+	for _, p := range a.providers {
+		options = append(options, option{Name: p.name, Title: p.title})
+	}
+	web.NewRequest(writer, request).
+		WithLocalizer(a.locales.Localizer(request.Header.Get("Accept-Language"))).
+		Set("Providers", options).
+		Set("Redirect", web.LocalRedirect(request.URL.Query().Get("redirect"))).
+		Render(http.StatusOK, a.view)
+}
+
+func (a *Auth) selectProvider(writer http.ResponseWriter, request *http.Request) {
+	p, ok := a.byName[chi.URLParam(request, "provider")]
+	if !ok {
+		http.NotFound(writer, request)
+		return
+	}
+	a.sessions.Put(request.Context(), sessionProvider, p.name)
+	// protected page will start login by selected provider
+	http.Redirect(writer, request, web.LocalRedirect(request.URL.Query().Get("redirect")), http.StatusSeeOther)
+}
+
+func (a *Auth) logout(p *provider) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		a.sessions.Remove(request.Context(), sessionProvider)
+		p.auth.ServeHTTP(writer, request)
+	})
+}
+
+func (a *Auth) newProvider(ctx context.Context, serverURL string, cfg Provider) (*provider, error) {
+	// each provider keeps tokens in own session to avoid mixing state between providers
+	providerSessions := scs.New()
+	providerSessions.Store = a.sessions.Store
+	providerSessions.Cookie.Name = "oidc_" + cfg.Name
+
+	p := &provider{
+		name:    cfg.Name,
+		title:   cfg.title(),
+		prefix:  cfg.callbackPrefix(),
+		mapping: cfg.mapping(),
+	}
+	auth, err := oidclogin.New(ctx, oidclogin.Config{
+		IssuerURL:      cfg.Issuer,
+		ClientID:       cfg.ClientID,
+		ClientSecret:   cfg.ClientSecret,
+		ServerURL:      serverURL,
+		Scopes:         cfg.scopes(),
+		CallbackPrefix: p.prefix,
+		SessionManager: providerSessions,
+		BeforeAuth: func(writer http.ResponseWriter, req *http.Request) error {
+			a.sessions.Put(req.Context(), sessionRedirect, req.URL.String())
+			return nil
+		},
+		PostAuth: func(writer http.ResponseWriter, req *http.Request, idToken *oidc.IDToken) error {
+			to := a.sessions.PopString(req.Context(), sessionRedirect)
+			if to != "" {
+				writer.Header().Set("Location", to)
+			}
+			return nil
+		},
+		Logger: logger(cfg.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.auth = auth
+	return p, nil
+}
+
+func (p *provider) withCredentials(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		token := oidclogin.Token(request)
+		if token == nil {
+			next.ServeHTTP(writer, request)
+			return
+		}
+		var values map[string]any
+		if err := token.Claims(&values); err != nil {
+			slog.Warn("failed parse OIDC claims", "provider", p.name, "error", err)
+		}
+		creds := p.mapping.Credentials(values)
+		if creds.User == "" {
+			creds.User = token.Subject
+		}
+		creds.Provider = p.name
+		next.ServeHTTP(writer, request.WithContext(schema.WithCredentials(request.Context(), creds)))
+	})
+}
+
+func logger(name string) oidclogin.Logger {
+	return oidclogin.LoggerFunc(func(level oidclogin.Level, message string) {
+		switch level {
+		case oidclogin.LogInfo:
+			slog.Info(message, "source", "oidc", "provider", name)
+		case oidclogin.LogWarn:
+			slog.Warn(message, "source", "oidc", "provider", name)
+		case oidclogin.LogError:
+			slog.Error(message, "source", "oidc", "provider", name)
+		default:
+			slog.Debug(message, "source", "oidc", "provider", name)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package oidcauth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/reddec/web-form/internal/claims"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidProvider = errors.New("invalid provider")
+
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// names which conflict with routes.
+var reservedNames = []string{"select", "callback", "logout"}
+
+// Provider configuration. Empty scopes and claims are replaced by defaults.
+type Provider struct {
+	Name         string   `yaml:"name"`          // unique provider name, used in callback path and policies
+	Title        string   `yaml:"title"`         // human-readable name for provider chooser, default is name
+	Issuer       string   `yaml:"issuer"`        // issuer URL (without .well-known)
+	ClientID     string   `yaml:"client_id"`     // OIDC client ID
+	ClientSecret string   `yaml:"client_secret"` // OIDC client secret
+	Scopes       []string `yaml:"scopes"`        // requested scopes, openid is always added
+	UserClaims   []string `yaml:"user_claims"`   // claims for user name, first non-empty is used
+	EmailClaim   string   `yaml:"email_claim"`   // claim for email
+	GroupsClaims []string `yaml:"groups_claims"` // claims for groups, all values are merged
+	// (optional) callback prefix. Default is Prefix + name + "/".
+	CallbackPrefix string `yaml:"-"`
+}
+
+// Validate provider name and required fields.
+func (p *Provider) Validate() error {
+	if !providerName.MatchString(p.Name) || slices.Contains(reservedNames, p.Name) {
+		return fmt.Errorf("%w: name %q should be lower-case letters, digits, - or _ and not one of %v", ErrInvalidProvider, p.Name, reservedNames)
+	}
+	if p.Issuer == "" {
+		return fmt.Errorf("%w: %q: issuer not set", ErrInvalidProvider, p.Name)
+	}
+	if p.ClientID == "" {
+		return fmt.Errorf("%w: %q: client ID not set", ErrInvalidProvider, p.Name)
+	}
+	return nil
+}
+
+func (p *Provider) title() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return p.Name
+}
+
+func (p *Provider) callbackPrefix() string {
+	if p.CallbackPrefix != "" {
+		return p.CallbackPrefix
+	}
+	return Prefix + p.Name + "/"
+}
+
+func (p *Provider) scopes() []string {
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile"}
+	}
+	if slices.Contains(scopes, oidc.ScopeOpenID) {
+		return scopes
+	}
+	return append([]string{oidc.ScopeOpenID}, scopes...)
+}
+
+func (p *Provider) mapping() *claims.Mapping {
+	m := &claims.Mapping{
+		User:   p.UserClaims,
+		Email:  p.EmailClaim,
+		Groups: p.GroupsClaims,
+	}
+	if len(m.User) == 0 {
+		m.User = []string{"preferred_username", "email", "sub"}
+	}
+	if m.Email == "" {
+		m.Email = "email"
+	}
+	if len(m.Groups) == 0 {
+		m.Groups = []string{"groups"}
+	}
+	return m
+}
+
+// ProvidersFromFile reads list of providers from YAML file.
+func ProvidersFromFile(file string) ([]Provider, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	var providers []Provider
+	if err := yaml.NewDecoder(f).Decode(&providers); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read %q: %w", file, err)
+	}
+	return providers, nil
+}
@@ -0,0 +1,121 @@
+package oidcauth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/reddec/web-form/internal/oidcauth"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	employees := newIssuer(t)
+	contractors := newIssuer(t)
+
+	sessions := scs.New()
+	auth, err := oidcauth.New(context.Background(), sessions, "https://forms.example.com", []oidcauth.Provider{
+		{Name: "employees", Title: "Employees", Issuer: employees.URL, ClientID: "forms"},
+		{Name: "contractors", Issuer: contractors.URL, ClientID: "forms-ext"},
+	})
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(sessions.LoadAndSave)
+	router.Mount(oidcauth.Prefix, auth)
+	router.With(auth.Secure).Get("/forms/demo", func(writer http.ResponseWriter, request *http.Request) {})
+
+	var cookies []*http.Cookie
+	call := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		cookies = append(cookies, rec.Result().Cookies()...)
+		return rec
+	}
+
+	rec := call("/forms/demo")
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/oauth2/select?redirect=%2Fforms%2Fdemo", rec.Header().Get("Location"))
+
+	rec = call("/oauth2/select?redirect=%2Fforms%2Fdemo")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Employees")
+	assert.Contains(t, rec.Body.String(), "contractors")
+	assert.Contains(t, rec.Body.String(), `href="select/contractors?redirect=%2Fforms%2Fdemo"`)
+	assert.Contains(t, rec.Body.String(), `<html lang="en">`)
+	assert.Contains(t, rec.Body.String(), "Choose how you want to sign in")
+
+	rec = call("/oauth2/select/unknown?redirect=%2Fforms%2Fdemo")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = call("/oauth2/select/contractors?redirect=https://evil.example.com")
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/", rec.Header().Get("Location"))
+
+	// selected provider starts login by own issuer and callback
+	rec = call("/forms/demo")
+	require.Equal(t, http.StatusFound, rec.Code)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, contractors.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+	assert.Equal(t, "forms-ext", location.Query().Get("client_id"))
+	assert.Equal(t, "https://forms.example.com/oauth2/contractors/callback", location.Query().Get("redirect_uri"))
+}
+
+func TestAuth_single(t *testing.T) {
+	issuer := newIssuer(t)
+	sessions := scs.New()
+	auth, err := oidcauth.New(context.Background(), sessions, "https://forms.example.com", []oidcauth.Provider{
+		{Name: "default", Issuer: issuer.URL, ClientID: "forms", CallbackPrefix: oidcauth.Prefix},
+	})
+	require.NoError(t, err)
+
+	handler := sessions.LoadAndSave(auth.Secure(http.NotFoundHandler()))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/forms/demo", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://forms.example.com/oauth2/callback", location.Query().Get("redirect_uri"))
+}
+
+func TestNew_invalid(t *testing.T) {
+	issuer := newIssuer(t)
+	for _, providers := range [][]oidcauth.Provider{
+		nil,
+		{{Name: "Bad Name", Issuer: issuer.URL, ClientID: "forms"}},
+		{{Name: "select", Issuer: issuer.URL, ClientID: "forms"}},
+		{{Name: "a", ClientID: "forms"}},
+		{{Name: "a", Issuer: issuer.URL}},
+		{{Name: "a", Issuer: issuer.URL, ClientID: "forms"}, {Name: "a", Issuer: issuer.URL, ClientID: "forms"}},
+	} {
+		_, err := oidcauth.New(context.Background(), scs.New(), "", providers)
+		assert.ErrorIs(t, err, oidcauth.ErrInvalidProvider)
+	}
+}
+
+func newIssuer(t *testing.T) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(map[string]any{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/keys",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
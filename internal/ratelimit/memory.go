@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const cleanupInterval = time.Minute
+
+// NewMemory creates in-memory limiter. Counters are not shared between instances.
+func NewMemory() *Memory {
+	return &Memory{counters: make(map[string]*counter), cleanup: time.Now()}
+}
+
+type Memory struct {
+	lock     sync.Mutex
+	counters map[string]*counter
+	cleanup  time.Time // last cleanup
+}
+
+type counter struct {
+	hits    int
+	expires time.Time
+}
+
+func (m *Memory) Hit(_ context.Context, key string, window time.Duration) (int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	m.removeExpired(now)
+
+	c, ok := m.counters[key]
+	if !ok || !now.Before(c.expires) {
+		c = &counter{expires: now.Add(window)}
+		m.counters[key] = c
+	}
+	c.hits++
+	return c.hits, nil
+}
+
+func (m *Memory) Hits(_ context.Context, key string) (int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	c, ok := m.counters[key]
+	if !ok || !time.Now().Before(c.expires) {
+		return 0, nil
+	}
+	return c.hits, nil
+}
+
+func (m *Memory) Undo(_ context.Context, key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	c, ok := m.counters[key]
+	if ok && time.Now().Before(c.expires) && c.hits > 0 {
+		c.hits--
+	}
+	return nil
+}
+
+func (m *Memory) removeExpired(now time.Time) {
+	if now.Sub(m.cleanup) < cleanupInterval {
+		return
+	}
+	m.cleanup = now
+	for key, c := range m.counters {
+		if !now.Before(c.expires) {
+			delete(m.counters, key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRate = errors.New("invalid rate, expected format <requests>/<duration>, for example 10/1m")
+
+// Limiter counts hits per key in fixed time windows.
+type Limiter interface {
+	// Hit registers hit for the key and returns number of hits in current window.
+	Hit(ctx context.Context, key string, window time.Duration) (int, error)
+	// Hits returns number of hits for the key in current window without registering new one.
+	Hits(ctx context.Context, key string) (int, error)
+	// Undo removes one hit of the key in current window (if any), for example after successful attempt.
+	Undo(ctx context.Context, key string) error
+}
+
+// Rate is maximum number of requests per time window. Zero rate means no limit.
+type Rate struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseRate parses rate in format <requests>/<duration>, for example 10/1m. Empty string means no limit.
+func ParseRate(value string) (Rate, error) {
+	if value == "" {
+		return Rate{}, nil
+	}
+	count, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return Rate{}, ErrInvalidRate
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || limit < 0 {
+		return Rate{}, fmt.Errorf("%w: requests: %q", ErrInvalidRate, count)
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || duration < 0 {
+		return Rate{}, fmt.Errorf("%w: duration: %q", ErrInvalidRate, window)
+	}
+	return Rate{Limit: limit, Window: duration}, nil
+}
+
+// Enabled returns true if rate defines limit.
+func (r Rate) Enabled() bool {
+	return r.Limit > 0 && r.Window > 0
+}
+
+func (r Rate) String() string {
+	if !r.Enabled() {
+		return ""
+	}
+	return strconv.Itoa(r.Limit) + "/" + r.Window.String()
+}
+
+func (r *Rate) UnmarshalText(text []byte) error {
+	v, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// UnmarshalFlag implements go-flags Unmarshaler.
+func (r *Rate) UnmarshalFlag(value string) error {
+	return r.UnmarshalText([]byte(value))
+}
+
+// Allow registers hit for the key and returns true if number of hits within the window doesn't exceed the rate.
+// Disabled rate always allowed.
+func Allow(ctx context.Context, limiter Limiter, key string, rate Rate) (bool, error) {
+	if !rate.Enabled() {
+		return true, nil
+	}
+	hits, err := limiter.Hit(ctx, key, rate.Window)
+	if err != nil {
+		return false, err
+	}
+	return hits <= rate.Limit, nil
+}
+
+// Exceeded returns true if number of hits within the window already reached the rate. Doesn't register new hit.
+// Disabled rate is never exceeded.
+func Exceeded(ctx context.Context, limiter Limiter, key string, rate Rate) (bool, error) {
+	if !rate.Enabled() {
+		return false, nil
+	}
+	hits, err := limiter.Hits(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return hits >= rate.Limit, nil
+}
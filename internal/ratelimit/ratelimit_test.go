@@ -0,0 +1,71 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	rate, err := ratelimit.ParseRate("10/1m")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Rate{Limit: 10, Window: time.Minute}, rate)
+	assert.True(t, rate.Enabled())
+	assert.Equal(t, "10/1m0s", rate.String())
+
+	rate, err = ratelimit.ParseRate("")
+	require.NoError(t, err)
+	assert.False(t, rate.Enabled())
+
+	for _, invalid := range []string{"10", "x/1m", "10/x", "-1/1m"} {
+		_, err = ratelimit.ParseRate(invalid)
+		assert.ErrorIs(t, err, ratelimit.ErrInvalidRate, invalid)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	limiter := ratelimit.NewMemory()
+	rate := ratelimit.Rate{Limit: 2, Window: 50 * time.Millisecond}
+
+	for i := 0; i < 2; i++ {
+		ok, err := ratelimit.Allow(ctx, limiter, "a", rate)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	exceeded, err := ratelimit.Exceeded(ctx, limiter, "a", rate)
+	require.NoError(t, err)
+	assert.True(t, exceeded)
+
+	ok, err := ratelimit.Allow(ctx, limiter, "a", rate)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// undo returns hit back
+	require.NoError(t, limiter.Undo(ctx, "a"))
+	require.NoError(t, limiter.Undo(ctx, "a"))
+	hits, err := limiter.Hits(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, hits)
+	require.NoError(t, limiter.Undo(ctx, "unknown"))
+
+	// keys are independent
+	ok, err = ratelimit.Allow(ctx, limiter, "b", rate)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// new window
+	time.Sleep(rate.Window)
+	exceeded, err = ratelimit.Exceeded(ctx, limiter, "a", rate)
+	require.NoError(t, err)
+	assert.False(t, exceeded)
+	ok, err = ratelimit.Allow(ctx, limiter, "a", rate)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
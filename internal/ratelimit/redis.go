@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const redisPrefix = "web-form:ratelimit:"
+
+// increments counter and sets expiration for the new window.
+//
+//nolint:gochecknoglobals
+var hitScript = redis.NewScript(1, `
+local hits = redis.call('INCR', KEYS[1])
+if hits == 1 then
+    redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return hits
+`)
+
+// decrements existing counter, keeps expiration.
+//
+//nolint:gochecknoglobals
+var undoScript = redis.NewScript(1, `
+local hits = tonumber(redis.call('GET', KEYS[1]) or '0')
+if hits > 0 then
+    redis.call('DECR', KEYS[1])
+end
+return 0
+`)
+
+// NewRedis creates limiter with counters in Redis, shared between instances.
+func NewRedis(pool *redis.Pool) *Redis {
+	return &Redis{pool: pool}
+}
+
+type Redis struct {
+	pool *redis.Pool
+}
+
+func (r *Redis) Hit(ctx context.Context, key string, window time.Duration) (int, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	hits, err := redis.Int(hitScript.DoContext(ctx, conn, redisPrefix+key, window.Milliseconds()))
+	if err != nil {
+		return 0, fmt.Errorf("increment counter: %w", err)
+	}
+	return hits, nil
+}
+
+func (r *Redis) Hits(ctx context.Context, key string) (int, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	hits, err := redis.Int(redis.DoContext(conn, ctx, "GET", redisPrefix+key))
+	if errors.Is(err, redis.ErrNil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get counter: %w", err)
+	}
+	return hits, nil
+}
+
+func (r *Redis) Undo(ctx context.Context, key string) error {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := undoScript.DoContext(ctx, conn, redisPrefix+key); err != nil {
+		return fmt.Errorf("decrement counter: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid API key definition")
+
+// Scopes of API keys.
+const (
+	ScopeSubmit = "submit" // submit forms (POST)
+	ScopeRead   = "read"   // view forms and listing (GET)
+)
+
+// APIKey for machine clients. Key uses the same formats as access codes (plain, hashed, env:, file:).
+type APIKey struct {
+	Name   string     // unique name of the key, used as user name in credentials and audit
+	Key    AccessCode // secret
+	Scopes []string   // allowed scopes, default is submit
+	Groups []string   // groups in credentials, available to policy
+}
+
+func (k *APIKey) UnmarshalYAML(value *yaml.Node) error {
+	type plain APIKey
+	var key plain
+	if err := value.Decode(&key); err != nil {
+		return err
+	}
+	if key.Name == "" {
+		return fmt.Errorf("%w: name required", ErrInvalidAPIKey)
+	}
+	if len(key.Key.value) == 0 {
+		return fmt.Errorf("%w: key %q: secret required", ErrInvalidAPIKey, key.Name)
+	}
+	if len(key.Scopes) == 0 {
+		key.Scopes = []string{ScopeSubmit}
+	}
+	for _, scope := range key.Scopes {
+		if scope != ScopeSubmit && scope != ScopeRead {
+			return fmt.Errorf("%w: key %q: unknown scope %q", ErrInvalidAPIKey, key.Name, scope)
+		}
+	}
+	*k = APIKey(key)
+	return nil
+}
+
+// Allows checks that key has the scope.
+func (k *APIKey) Allows(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Credentials of service identity.
+func (k *APIKey) Credentials() *Credentials {
+	return &Credentials{
+		User:    k.Name,
+		Groups:  k.Groups,
+		Service: true,
+	}
+}
+
+// APIKeys is a list of API keys.
+type APIKeys []APIKey
+
+// Find key by secret. Returns nil if nothing found.
+func (ak APIKeys) Find(secret string) *APIKey {
+	if secret == "" {
+		return nil
+	}
+	for i := range ak {
+		if ak[i].Key.Verify(secret) {
+			return &ak[i]
+		}
+	}
+	return nil
+}
+
+// APIKeysFromFile reads list of API keys from YAML file.
+func APIKeysFromFile(file string) (APIKeys, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	var keys APIKeys
+	if err := yaml.NewDecoder(f).Decode(&keys); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read %q: %w", file, err)
+	}
+	return keys, nil
+}
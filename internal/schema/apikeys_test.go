@@ -0,0 +1,46 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/reddec/web-form/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	const txt = `
+name: orders
+api_keys:
+  - name: crm
+    key: crm-secret
+  - name: reader
+    key: reader-secret
+    scopes: [read]
+`
+	forms, err := schema.FormsFromStream(strings.NewReader(txt))
+	require.NoError(t, err)
+	keys := forms[0].APIKeys
+	require.Len(t, keys, 2)
+
+	crm := keys.Find("crm-secret")
+	require.NotNil(t, crm)
+	assert.True(t, crm.Allows(schema.ScopeSubmit), "default scope")
+	assert.False(t, crm.Allows(schema.ScopeRead))
+	assert.Equal(t, &schema.Credentials{User: "crm", Service: true}, crm.Credentials())
+
+	assert.Equal(t, "reader", keys.Find("reader-secret").Name)
+	assert.Nil(t, keys.Find("unknown"))
+	assert.Nil(t, keys.Find(""))
+
+	for _, invalid := range []string{
+		"api_keys: [{key: secret}]",
+		"api_keys: [{name: crm}]",
+		"api_keys: [{name: crm, key: secret, scopes: [admin]}]",
+	} {
+		_, err := schema.FormsFromStream(strings.NewReader(invalid))
+		assert.ErrorIs(t, err, schema.ErrInvalidAPIKey, invalid)
+	}
+}
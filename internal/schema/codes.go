@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidHash      = errors.New("invalid access code hash")
+	ErrEmptyCode        = errors.New("empty access code")
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")
+)
+
+// Hash algorithms for access codes.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// argon2id parameters for new hashes (OWASP recommendations).
+const (
+	argonTime    = 2
+	argonMemory  = 19 * 1024
+	argonThreads = 1
+	argonKeySize = 32
+	argonSalt    = 16
+)
+
+// AccessCodes is a list of access codes. Each code in configuration can be:
+//
+//   - bcrypt hash: $2a$..., $2b$..., $2y$...
+//   - argon2id hash in PHC format: $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+//   - env:NAME - plain code from environment variable
+//   - file:/path - plain code from file (trailing spaces and new lines are ignored)
+//   - plain code
+type AccessCodes []AccessCode
+
+// Has checks that code matches any of access codes. All codes are checked to keep response time stable.
+func (ac AccessCodes) Has(code string) bool {
+	if code == "" {
+		return false
+	}
+	var found bool
+	for _, c := range ac {
+		if c.Verify(code) {
+			found = true
+		}
+	}
+	return found
+}
+
+type codeKind int
+
+const (
+	codePlain codeKind = iota
+	codeBcrypt
+	codeArgon2id
+)
+
+// AccessCode is single access code: plain or hashed.
+type AccessCode struct {
+	kind    codeKind
+	value   []byte // plain code or hash
+	salt    []byte // argon2id only
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// PlainCode creates plain text access code.
+func PlainCode(code string) AccessCode {
+	return AccessCode{kind: codePlain, value: []byte(code)}
+}
+
+// ParseCode parses access code definition, see AccessCodes for supported formats.
+func ParseCode(text string) (AccessCode, error) {
+	switch {
+	case strings.HasPrefix(text, "env:"):
+		name := strings.TrimPrefix(text, "env:")
+		value := os.Getenv(name)
+		if value == "" {
+			return AccessCode{}, fmt.Errorf("%w: environment variable %q not set", ErrEmptyCode, name)
+		}
+		return PlainCode(value), nil
+	case strings.HasPrefix(text, "file:"):
+		file := strings.TrimPrefix(text, "file:")
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return AccessCode{}, fmt.Errorf("read access code: %w", err)
+		}
+		value := strings.TrimRight(string(content), " \t\r\n")
+		if value == "" {
+			return AccessCode{}, fmt.Errorf("%w: file %q", ErrEmptyCode, file)
+		}
+		return PlainCode(value), nil
+	case strings.HasPrefix(text, "$2a$"), strings.HasPrefix(text, "$2b$"), strings.HasPrefix(text, "$2y$"):
+		if _, err := bcrypt.Cost([]byte(text)); err != nil {
+			return AccessCode{}, fmt.Errorf("%w: %w", ErrInvalidHash, err)
+		}
+		return AccessCode{kind: codeBcrypt, value: []byte(text)}, nil
+	case strings.HasPrefix(text, "$argon2id$"):
+		return parseArgon2id(text)
+	case text == "":
+		return AccessCode{}, ErrEmptyCode
+	default:
+		return PlainCode(text), nil
+	}
+}
+
+func (c *AccessCode) UnmarshalText(text []byte) error {
+	v, err := ParseCode(string(text))
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// Verify code. Plain codes are compared in constant time.
+func (c *AccessCode) Verify(code string) bool {
+	switch c.kind {
+	case codeBcrypt:
+		return bcrypt.CompareHashAndPassword(c.value, []byte(code)) == nil
+	case codeArgon2id:
+		hash := argon2.IDKey([]byte(code), c.salt, c.time, c.memory, c.threads, uint32(len(c.value)))
+		return subtle.ConstantTimeCompare(hash, c.value) == 1
+	default:
+		// compare digests to not leak length of the code
+		expected := sha256.Sum256(c.value)
+		actual := sha256.Sum256([]byte(code))
+		return subtle.ConstantTimeCompare(expected[:], actual[:]) == 1
+	}
+}
+
+// HashCode hashes access code by the algorithm (bcrypt or argon2id). Result can be used in form definition.
+func HashCode(code string, algorithm string) (string, error) {
+	if code == "" {
+		return "", ErrEmptyCode
+	}
+	switch algorithm {
+	case AlgorithmBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		return string(hash), err
+	case AlgorithmArgon2id:
+		var salt [argonSalt]byte
+		if _, err := io.ReadFull(rand.Reader, salt[:]); err != nil {
+			return "", fmt.Errorf("generate salt: %w", err)
+		}
+		hash := argon2.IDKey([]byte(code), salt[:], argonTime, argonMemory, argonThreads, argonKeySize)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+			base64.RawStdEncoding.EncodeToString(salt[:]), base64.RawStdEncoding.EncodeToString(hash)), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algorithm)
+	}
+}
+
+func parseArgon2id(text string) (AccessCode, error) {
+	// $argon2id$v=19$m=19456,t=2,p=1$salt$hash
+	parts := strings.Split(text, "$")
+	if len(parts) != 6 {
+		return AccessCode{}, ErrInvalidHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return AccessCode{}, fmt.Errorf("%w: unsupported argon2 version", ErrInvalidHash)
+	}
+	code := AccessCode{kind: codeArgon2id}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &code.memory, &code.time, &code.threads); err != nil {
+		return AccessCode{}, fmt.Errorf("%w: parameters: %w", ErrInvalidHash, err)
+	}
+	if code.memory == 0 || code.time == 0 || code.threads == 0 {
+		return AccessCode{}, fmt.Errorf("%w: zero parameters", ErrInvalidHash)
+	}
+	var err error
+	code.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return AccessCode{}, fmt.Errorf("%w: salt: %w", ErrInvalidHash, err)
+	}
+	code.value, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(code.value) == 0 {
+		return AccessCode{}, fmt.Errorf("%w: hash", ErrInvalidHash)
+	}
+	return code, nil
+}
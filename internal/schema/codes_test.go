@@ -0,0 +1,62 @@
+package schema_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/reddec/web-form/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessCodes(t *testing.T) {
+	bcryptHash, err := schema.HashCode("bcrypt-code", schema.AlgorithmBcrypt)
+	require.NoError(t, err)
+	argonHash, err := schema.HashCode("argon-code", schema.AlgorithmArgon2id)
+	require.NoError(t, err)
+
+	t.Setenv("TEST_ACCESS_CODE", "env-code")
+	file := filepath.Join(t.TempDir(), "code")
+	require.NoError(t, os.WriteFile(file, []byte("file-code\n"), 0600))
+
+	txt := `
+name: secured
+codes:
+  - plain-code
+  - '` + bcryptHash + `'
+  - '` + argonHash + `'
+  - env:TEST_ACCESS_CODE
+  - file:` + file + `
+`
+	forms, err := schema.FormsFromStream(strings.NewReader(txt))
+	require.NoError(t, err)
+	require.Len(t, forms, 1)
+
+	codes := forms[0].Codes
+	for _, code := range []string{"plain-code", "bcrypt-code", "argon-code", "env-code", "file-code"} {
+		assert.True(t, codes.Has(code), code)
+	}
+	for _, code := range []string{"", "plain", "plain-code ", bcryptHash, argonHash, "env:TEST_ACCESS_CODE"} {
+		assert.False(t, codes.Has(code), code)
+	}
+}
+
+func TestParseCode(t *testing.T) {
+	_, err := schema.ParseCode("env:TEST_UNKNOWN_ACCESS_CODE")
+	assert.ErrorIs(t, err, schema.ErrEmptyCode)
+
+	_, err = schema.ParseCode("file:" + filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+
+	_, err = schema.ParseCode("$2a$broken")
+	assert.ErrorIs(t, err, schema.ErrInvalidHash)
+
+	_, err = schema.ParseCode("$argon2id$v=19$m=0,t=1,p=1$c2FsdA$aGFzaA")
+	assert.ErrorIs(t, err, schema.ErrInvalidHash)
+
+	_, err = schema.HashCode("code", "md5")
+	assert.ErrorIs(t, err, schema.ErrUnknownAlgorithm)
+}
@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"strings"
+)
+
+// FormLocale is translation of the form. Empty values are not translated.
+type FormLocale struct {
+	Title       string                   // translated title
+	Description Template[RequestContext] // (markdown) translated description
+	Success     Template[ResultContext]  // translated message for success
+	Failed      Template[ResultContext]  // translated message for failed
+}
+
+// FieldLocale is translation of the field. Empty values are not translated.
+type FieldLocale struct {
+	Label       string            // translated label
+	Description string            // translated description
+	Options     map[string]string // translated option labels by option value (or label if value not set)
+}
+
+// Localize returns copy of the form with texts translated to the language. Exact language (ex: pt-BR) is preferred,
+// otherwise base language (ex: pt) is used. Stored values of options are not changed.
+func (f Form) Localize(lang string) Form {
+	if tr, ok := findLocale(f.I18n, lang); ok {
+		if tr.Title != "" {
+			f.Title = tr.Title
+		}
+		if tr.Description.Valid {
+			f.Description = tr.Description
+		}
+		if tr.Success.Valid {
+			f.Success = tr.Success
+		}
+		if tr.Failed.Valid {
+			f.Failed = tr.Failed
+		}
+	}
+
+	var fields []Field
+	for i, field := range f.Fields {
+		tr, ok := findLocale(field.I18n, lang)
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = append([]Field(nil), f.Fields...)
+		}
+		fields[i] = field.localize(tr)
+	}
+	if fields != nil {
+		f.Fields = fields
+	}
+	return f
+}
+
+// Languages of form and fields translations, may contain duplicates.
+func (f *Form) Languages() []string {
+	var ans = make([]string, 0, len(f.I18n))
+	for lang := range f.I18n {
+		ans = append(ans, lang)
+	}
+	for _, field := range f.Fields {
+		for lang := range field.I18n {
+			ans = append(ans, lang)
+		}
+	}
+	return ans
+}
+
+func (f Field) localize(tr FieldLocale) Field {
+	if tr.Label != "" {
+		f.Label = tr.Label
+	}
+	if tr.Description != "" {
+		f.Description = tr.Description
+	}
+	if len(tr.Options) == 0 {
+		return f
+	}
+	var options = make([]Option, 0, len(f.Options))
+	for _, opt := range f.Options {
+		if opt.Value == "" {
+			// label is used as value - keep it
+			opt.Value = opt.Label
+		}
+		if label, ok := tr.Options[opt.Value]; ok {
+			opt.Label = label
+		}
+		options = append(options, opt)
+	}
+	f.Options = options
+	return f
+}
+
+func findLocale[T any](translations map[string]T, lang string) (T, bool) {
+	base, _, _ := strings.Cut(lang, "-")
+	var fallback *T
+	for name, v := range translations {
+		v := v
+		if strings.EqualFold(name, lang) {
+			return v, true
+		}
+		if strings.EqualFold(name, base) {
+			fallback = &v
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	var zero T
+	return zero, false
+}
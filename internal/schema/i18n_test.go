@@ -0,0 +1,65 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/reddec/web-form/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForm_Localize(t *testing.T) {
+	forms, err := schema.FormsFromStream(strings.NewReader(`
+name: survey
+title: Survey
+description: Tell us
+fields:
+  - name: color
+    label: Color
+    description: Favorite color
+    options:
+      - label: Red
+      - label: Green
+        value: green
+    i18n:
+      pt:
+        label: Cor
+        options:
+          Red: Vermelho
+  - name: comment
+i18n:
+  pt-BR:
+    title: Pesquisa
+  pt:
+    title: Inquérito
+    description: Conte-nos
+`))
+	require.NoError(t, err)
+	form := forms[0]
+
+	br := form.Localize("pt-BR")
+	assert.Equal(t, "Pesquisa", br.Title)
+	assert.Equal(t, "Tell us", mustString(t, br.Description))
+	assert.Equal(t, "Cor", br.Fields[0].Label)
+	assert.Equal(t, "Favorite color", br.Fields[0].Description)
+	assert.Equal(t, []schema.Option{{Label: "Vermelho", Value: "Red"}, {Label: "Green", Value: "green"}}, br.Fields[0].Options)
+	assert.Equal(t, schema.OptionValues(form.Fields[0].Options...), schema.OptionValues(br.Fields[0].Options...))
+
+	pt := form.Localize("pt")
+	assert.Equal(t, "Inquérito", pt.Title)
+	assert.Equal(t, "Conte-nos", mustString(t, pt.Description))
+
+	// original form is not changed
+	en := form.Localize("en")
+	assert.Equal(t, "Survey", en.Title)
+	assert.Equal(t, "Color", form.Fields[0].Label)
+	assert.Equal(t, "", form.Fields[0].Options[0].Value)
+}
+
+func mustString(t *testing.T, tpl schema.Template[schema.RequestContext]) string {
+	v, err := tpl.String(&schema.RequestContext{})
+	require.NoError(t, err)
+	return v
+}
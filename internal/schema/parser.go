@@ -9,7 +9,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/google/cel-go/cel"
 	"github.com/reddec/web-form/internal/utils"
 )
 
@@ -17,6 +16,10 @@ var (
 	ErrInvalidType   = errors.New("field type invalid")
 	ErrRequiredField = errors.New("required field not set")
 	ErrWrongPattern  = errors.New("doesn't match pattern")
+	ErrInvalidPolicy = errors.New("hook policy invalid")
+	ErrInvalidOption = errors.New("selected not allowed option")
+	ErrInvalidValue  = errors.New("invalid value")
+	ErrUnknownField  = errors.New("unknown field")
 )
 
 func (t *Type) UnmarshalText(text []byte) error {
@@ -32,6 +35,19 @@ func (t *Type) UnmarshalText(text []byte) error {
 	return nil
 }
 
+func (hp *HookPolicy) UnmarshalText(text []byte) error {
+	v := HookPolicy(text)
+	switch v {
+	case "":
+		*hp = HookBlock
+	case HookBlock, HookAllow:
+		*hp = v
+	default:
+		return fmt.Errorf("on_error %q: %w", v, ErrInvalidPolicy)
+	}
+	return nil
+}
+
 func (t Type) Parse(value string, locale *time.Location) (any, error) {
 	switch t {
 	case TypeString:
@@ -43,14 +59,26 @@ func (t Type) Parse(value string, locale *time.Location) (any, error) {
 	case TypeBoolean:
 		return strconv.ParseBool(value)
 	case TypeDate:
-		return time.ParseInLocation("2006-01-02", value, locale)
+		return parseTime("2006-01-02", value, locale)
 	case TypeDateTime:
-		return time.ParseInLocation("2006-01-02T15:04", value, locale) // html type
+		return parseTime("2006-01-02T15:04", value, locale) // html type
 	default:
 		return value, nil
 	}
 }
 
+// parseTime parses value in layout or, as fallback, in RFC3339 (format of times in JSON, for example from hooks).
+func parseTime(layout string, value string, locale *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(layout, value, locale)
+	if err == nil {
+		return t, nil
+	}
+	if rt, rfcErr := time.Parse(time.RFC3339, value); rfcErr == nil {
+		return rt, nil
+	}
+	return t, err
+}
+
 func (f *Field) Parse(value string, locale *time.Location, viewCtx *RequestContext) (any, error) {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -86,11 +114,7 @@ func OptionValues(options ...Option) utils.Set[string] {
 }
 
 func (p *Policy) UnmarshalText(text []byte) error {
-	env, err := cel.NewEnv(
-		cel.Variable("user", cel.StringType),
-		cel.Variable("email", cel.StringType),
-		cel.Variable("groups", cel.ListType(cel.StringType)),
-	)
+	env, err := newPolicyEnv()
 	if err != nil {
 		return fmt.Errorf("create CEL env: %w", err)
 	}
@@ -159,20 +183,7 @@ func ParseForm(definition *Form, tzLocation *time.Location, viewCtx *RequestCont
 			values = append(values, v)
 		}
 
-		if len(field.Options) > 0 {
-			// we need to check that all values belong to allowed values before parsing
-			// since we are using plain text comparison.
-			options := OptionValues(field.Options...)
-			if !options.Has(values...) {
-				fieldErrors = append(fieldErrors, FieldError{
-					Name:  field.Name,
-					Error: errors.New("selected not allowed option"),
-				})
-				continue
-			}
-		}
-
-		parsedValues, err := parseValues(values, &field, tzLocation, viewCtx)
+		value, ok, err := ParseValues(&field, values, tzLocation, viewCtx)
 		if err != nil {
 			fieldErrors = append(fieldErrors, FieldError{
 				Name:  field.Name,
@@ -180,26 +191,45 @@ func ParseForm(definition *Form, tzLocation *time.Location, viewCtx *RequestCont
 			})
 			continue
 		}
-
-		if field.Required && len(parsedValues) == 0 {
-			// corner case - empty array for required field. Can happen if multi-select and nothing selected.
-			// for empty values it will be checked by field parser.
-			//
-			// We also need parse first to exclude empty values.
-			fieldErrors = append(fieldErrors, FieldError{
-				Name:  field.Name,
-				Error: errors.New("required field is not provided"),
-			})
-			continue
+		if ok {
+			fields[field.Name] = value
 		}
+	}
+	return fields, fieldErrors
+}
 
-		if field.Multiple {
-			fields[field.Name] = parsedValues
-		} else if len(parsedValues) > 0 {
-			fields[field.Name] = parsedValues[0]
+// ParseValues converts raw values of the field to the typed value (slice for multiple fields) and validates them
+// (options, pattern, required). Returns false if value is not set.
+func ParseValues(field *Field, values []string, tzLocation *time.Location, viewCtx *RequestContext) (any, bool, error) {
+	if len(field.Options) > 0 {
+		// we need to check that all values belong to allowed values before parsing
+		// since we are using plain text comparison.
+		options := OptionValues(field.Options...)
+		if !options.Has(values...) {
+			return nil, false, ErrInvalidOption
 		}
 	}
-	return fields, fieldErrors
+
+	parsedValues, err := parseValues(values, field, tzLocation, viewCtx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if field.Required && len(parsedValues) == 0 {
+		// corner case - empty array for required field. Can happen if multi-select and nothing selected.
+		// for empty values it will be checked by field parser.
+		//
+		// We also need parse first to exclude empty values.
+		return nil, false, ErrRequiredField
+	}
+
+	if field.Multiple {
+		return parsedValues, true, nil
+	}
+	if len(parsedValues) > 0 {
+		return parsedValues[0], true, nil
+	}
+	return nil, false, nil
 }
 
 func parseValues(values []string, field *Field, tzLocation *time.Location, viewCtx *RequestContext) ([]any, error) {
@@ -0,0 +1,140 @@
+package schema
+
+import (
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// PolicyContext is request details available to policy.
+type PolicyContext struct {
+	Credentials *Credentials // optional user credentials
+	IP          string       // client IP
+	Headers     http.Header  // request headers
+	Code        string       // access code (if any)
+}
+
+// Allows checks permission for the request. Anonymous requests (without credentials) are prohibited if form requires
+// authentication, otherwise they are allowed without evaluating policy. Always allowed for nil policy, and always
+// prohibited if policy returns non-boolean value or fails.
+func (f *Form) Allows(pc *PolicyContext) bool {
+	creds := pc.Credentials
+	if creds == nil {
+		return !f.RequireAuth
+	}
+	if f.Policy == nil {
+		return true
+	}
+	var headers = make(map[string]string, len(pc.Headers))
+	for k, v := range pc.Headers {
+		if len(v) > 0 {
+			headers[strings.ToLower(k)] = v[0]
+		}
+	}
+	out, _, err := f.Policy.Eval(map[string]any{
+		"user":     creds.User,
+		"email":    creds.Email,
+		"groups":   creds.Groups,
+		"service":  creds.Service,
+		"claims":   claimsOf(creds),
+		"provider": creds.Provider,
+		"ip":       pc.IP,
+		"headers":  headers,
+		"code":     pc.Code,
+		"form":     f.Name,
+	})
+	if err != nil {
+		slog.Error("failed evaluate policy", "form", f.Name, "error", err)
+		return false
+	}
+
+	v, ok := out.ConvertToType(cel.BoolType).Value().(bool)
+	return v && ok
+}
+
+// claimsOf returns claims or empty map to keep CEL expressions safe.
+func claimsOf(creds *Credentials) map[string]any {
+	if creds.Claims == nil {
+		return map[string]any{}
+	}
+	return creds.Claims
+}
+
+func newPolicyEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("user", cel.StringType),
+		cel.Variable("email", cel.StringType),
+		cel.Variable("groups", cel.ListType(cel.StringType)),
+		cel.Variable("service", cel.BoolType),
+		cel.Variable("claims", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("provider", cel.StringType),
+		cel.Variable("ip", cel.StringType),
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("code", cel.StringType),
+		cel.Variable("form", cel.StringType),
+		cel.Function("now",
+			cel.Overload("now", nil, cel.TimestampType,
+				cel.FunctionBinding(func(...ref.Val) ref.Val {
+					return types.Timestamp{Time: time.Now()}
+				}),
+			),
+		),
+		cel.Function("inCIDR",
+			cel.Overload("inCIDR_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(ip, cidr ref.Val) ref.Val {
+					return types.Bool(inCIDR(string(ip.(types.String)), string(cidr.(types.String))))
+				}),
+			),
+			cel.Overload("inCIDR_string_list", []*cel.Type{cel.StringType, cel.ListType(cel.StringType)}, cel.BoolType,
+				cel.BinaryBinding(func(ip, list ref.Val) ref.Val {
+					var found bool
+					iterate(list, func(item ref.Val) {
+						found = found || inCIDR(string(ip.(types.String)), string(item.(types.String)))
+					})
+					return types.Bool(found)
+				}),
+			),
+		),
+		cel.Function("hasAnyGroup",
+			cel.Overload("hasAnyGroup_list_list", []*cel.Type{cel.ListType(cel.StringType), cel.ListType(cel.StringType)}, cel.BoolType,
+				cel.BinaryBinding(func(groups, expected ref.Val) ref.Val {
+					container := groups.(traits.Container)
+					var found bool
+					iterate(expected, func(item ref.Val) {
+						found = found || container.Contains(item) == types.True
+					})
+					return types.Bool(found)
+				}),
+			),
+		),
+	)
+}
+
+// inCIDR checks that IP is in network (CIDR) or equal to IP. Invalid values are never matched.
+func inCIDR(ip string, cidr string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	if !strings.Contains(cidr, "/") {
+		other, err := netip.ParseAddr(cidr)
+		return err == nil && other.Unmap() == addr
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	return err == nil && prefix.Contains(addr)
+}
+
+func iterate(list ref.Val, fn func(item ref.Val)) {
+	it := list.(traits.Iterable).Iterator()
+	for it.HasNext() == types.True {
+		fn(it.Next())
+	}
+}
@@ -0,0 +1,77 @@
+package schema_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/reddec/web-form/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForm_Allows(t *testing.T) {
+	parse := func(policy string) *schema.Form {
+		forms, err := schema.FormsFromStream(strings.NewReader("name: orders\npolicy: '" + policy + "'"))
+		require.NoError(t, err)
+		return &forms[0]
+	}
+	user := &schema.Credentials{User: "alice", Groups: []string{"dev", "ops"}}
+	anyone := &schema.Credentials{User: "bob"}
+
+	t.Run("anonymous", func(t *testing.T) {
+		// existing deployments without authentication keep working with policies
+		form := parse(`user == "alice" || hasAnyGroup(groups, ["admin"])`)
+		assert.True(t, form.Allows(&schema.PolicyContext{}))
+		assert.True(t, form.IsAllowed(nil))
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: user}))
+		assert.False(t, form.Allows(&schema.PolicyContext{Credentials: anyone}))
+	})
+
+	t.Run("require_auth", func(t *testing.T) {
+		forms, err := schema.FormsFromStream(strings.NewReader("name: orders\nrequire_auth: true"))
+		require.NoError(t, err)
+		form := &forms[0]
+		assert.False(t, form.Allows(&schema.PolicyContext{}))
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: anyone}))
+
+		form.Policy = parse(`user == "alice"`).Policy
+		assert.False(t, form.Allows(&schema.PolicyContext{}))
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: user}))
+		assert.False(t, form.Allows(&schema.PolicyContext{Credentials: anyone}))
+	})
+
+	t.Run("inCIDR", func(t *testing.T) {
+		form := parse(`inCIDR(ip, "10.0.0.0/8") || inCIDR(ip, ["192.0.2.1", "2001:db8::/32"])`)
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: anyone, IP: "10.1.2.3"}))
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: anyone, IP: "192.0.2.1"}))
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: anyone, IP: "2001:db8::1"}))
+		assert.False(t, form.Allows(&schema.PolicyContext{Credentials: anyone, IP: "192.0.2.2"}))
+		assert.False(t, form.Allows(&schema.PolicyContext{Credentials: anyone, IP: "garbage"}))
+	})
+
+	t.Run("hasAnyGroup", func(t *testing.T) {
+		form := parse(`hasAnyGroup(groups, ["admin", "ops"])`)
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: user}))
+		assert.False(t, form.Allows(&schema.PolicyContext{Credentials: &schema.Credentials{Groups: []string{"dev"}}}))
+	})
+
+	t.Run("provider", func(t *testing.T) {
+		form := parse(`provider == "employees" || "contractor" in groups`)
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: &schema.Credentials{Provider: "employees"}}))
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: &schema.Credentials{Provider: "partners", Groups: []string{"contractor"}}}))
+		assert.False(t, form.Allows(&schema.PolicyContext{Credentials: &schema.Credentials{Provider: "partners"}}))
+	})
+
+	t.Run("request", func(t *testing.T) {
+		form := parse(`form == "orders" && code == "vip" && headers["x-team"] == "red"`)
+		assert.True(t, form.Allows(&schema.PolicyContext{Credentials: anyone, Code: "vip", Headers: http.Header{"X-Team": {"red"}}}))
+		assert.False(t, form.Allows(&schema.PolicyContext{Credentials: anyone, Code: "vip"}))
+	})
+
+	t.Run("now", func(t *testing.T) {
+		assert.True(t, parse(`now() > timestamp("2000-01-01T00:00:00Z")`).Allows(&schema.PolicyContext{Credentials: anyone}))
+		assert.True(t, parse(`now().getHours("UTC") < 24`).Allows(&schema.PolicyContext{Credentials: anyone}))
+	})
+}
@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidWeekday = errors.New("weekday invalid")
+	ErrInvalidClock   = errors.New("time of day invalid, expected HH:MM")
+)
+
+// Status of form availability.
+type Status string
+
+const (
+	StatusOpen     Status = "open"     // form accepts submissions
+	StatusUpcoming Status = "upcoming" // form is not yet open, but will be
+	StatusClosed   Status = "closed"   // form is closed and will not be open again
+)
+
+// Availability of the form at the specific moment.
+type Availability struct {
+	Status   Status
+	OpensAt  *time.Time // optional time when form opens
+	ClosesAt *time.Time // optional time when form closes
+	NextOpen *time.Time // nearest time when form will be open, set only for upcoming forms
+}
+
+func (a Availability) Open() bool {
+	return a.Status == StatusOpen
+}
+
+// Schedule of recurring weekly windows when form is open.
+type Schedule struct {
+	Timezone Location // timezone for windows, default is server local
+	Windows  []Window // if not set - form is always open
+}
+
+// Window of time during the week.
+type Window struct {
+	Days []Weekday // days of week, if not set - every day
+	From Clock     // start of window (inclusive)
+	To   Clock     // end of window (exclusive); if not after From - window ends next day
+}
+
+// Availability of the form at the moment. Form is open if current time is between OpensAt and ClosesAt (if set)
+// and within one of schedule windows (if set).
+func (f *Form) Availability(now time.Time) Availability {
+	ans := Availability{
+		Status:   StatusOpen,
+		OpensAt:  f.OpensAt,
+		ClosesAt: f.ClosesAt,
+	}
+	if f.ClosesAt != nil && !now.Before(*f.ClosesAt) {
+		ans.Status = StatusClosed
+		return ans
+	}
+
+	from := now
+	if f.OpensAt != nil && now.Before(*f.OpensAt) {
+		from = *f.OpensAt
+	}
+
+	next, ok := f.Schedule.next(from)
+	if !ok || (f.ClosesAt != nil && !next.Before(*f.ClosesAt)) {
+		ans.Status = StatusClosed
+		return ans
+	}
+	if next.After(now) {
+		ans.Status = StatusUpcoming
+		ans.NextOpen = &next
+	}
+	return ans
+}
+
+// next returns the earliest time not before t when schedule is open.
+func (s *Schedule) next(t time.Time) (time.Time, bool) {
+	if len(s.Windows) == 0 || s.contains(t) {
+		return t, true
+	}
+	local := t.In(s.Timezone.Get())
+	var best time.Time
+	for day := 0; day <= 7; day++ {
+		date := time.Date(local.Year(), local.Month(), local.Day()+day, 0, 0, 0, 0, local.Location())
+		for _, w := range s.Windows {
+			if !w.matches(date.Weekday()) {
+				continue
+			}
+			start := w.From.On(date)
+			if start.After(t) && (best.IsZero() || start.Before(best)) {
+				best = start
+			}
+		}
+		if !best.IsZero() {
+			return best, true
+		}
+	}
+	return best, false
+}
+
+func (s *Schedule) contains(t time.Time) bool {
+	local := t.In(s.Timezone.Get())
+	clock := Clock(time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second)
+	today := local.Weekday()
+	yesterday := (today + 6) % 7 //nolint:gomnd
+	for _, w := range s.Windows {
+		if w.From < w.To {
+			if w.matches(today) && clock >= w.From && clock < w.To {
+				return true
+			}
+			continue
+		}
+		// overnight window
+		if (w.matches(today) && clock >= w.From) || (w.matches(yesterday) && clock < w.To) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Window) matches(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if time.Weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Weekday in YAML as name (mon, monday, Monday).
+type Weekday time.Weekday
+
+func (wd *Weekday) UnmarshalText(text []byte) error {
+	name := strings.ToLower(string(text))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			*wd = Weekday(d)
+			return nil
+		}
+	}
+	return fmt.Errorf("%q: %w", text, ErrInvalidWeekday)
+}
+
+// Clock is time of day as offset from midnight, in YAML as HH:MM.
+type Clock time.Duration
+
+func (c *Clock) UnmarshalText(text []byte) error {
+	v, err := time.Parse("15:04", string(text))
+	if err != nil {
+		return fmt.Errorf("%q: %w", text, ErrInvalidClock)
+	}
+	*c = Clock(time.Duration(v.Hour())*time.Hour + time.Duration(v.Minute())*time.Minute)
+	return nil
+}
+
+// On returns time of the clock on the date.
+func (c Clock) On(date time.Time) time.Time {
+	h := time.Duration(c) / time.Hour
+	m := (time.Duration(c) % time.Hour) / time.Minute
+	return time.Date(date.Year(), date.Month(), date.Day(), int(h), int(m), 0, 0, date.Location())
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", time.Duration(c)/time.Hour, (time.Duration(c)%time.Hour)/time.Minute)
+}
+
+// Location is timezone in YAML as IANA name (ex: Europe/Berlin).
+type Location struct {
+	*time.Location
+}
+
+func (l *Location) UnmarshalText(text []byte) error {
+	v, err := time.LoadLocation(string(text))
+	if err != nil {
+		return fmt.Errorf("timezone %q: %w", text, err)
+	}
+	l.Location = v
+	return nil
+}
+
+// Get location or server local if not set.
+func (l Location) Get() *time.Location {
+	if l.Location == nil {
+		return time.Local
+	}
+	return l.Location
+}
@@ -0,0 +1,74 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForm_Availability(t *testing.T) {
+	const txt = `
+name: period
+opens_at: 2023-10-01T09:00:00Z
+closes_at: "2023-10-31T18:00:00Z"
+---
+name: weekly
+schedule:
+  timezone: UTC
+  windows:
+    - days: [mon, Wednesday]
+      from: "09:00"
+      to: "18:00"
+    - days: [fri]
+      from: "22:00"
+      to: "02:00"
+`
+	forms, err := schema.FormsFromStream(strings.NewReader(txt))
+	require.NoError(t, err)
+	require.Len(t, forms, 2)
+	period, weekly := forms[0], forms[1]
+
+	at := func(value string) time.Time {
+		v, err := time.Parse(time.RFC3339, value)
+		require.NoError(t, err)
+		return v
+	}
+
+	t.Run("period", func(t *testing.T) {
+		a := period.Availability(at("2023-09-30T00:00:00Z"))
+		assert.Equal(t, schema.StatusUpcoming, a.Status)
+		require.NotNil(t, a.NextOpen)
+		assert.Equal(t, at("2023-10-01T09:00:00Z"), *a.NextOpen)
+
+		assert.True(t, period.Availability(at("2023-10-01T09:00:00Z")).Open())
+		assert.Equal(t, schema.StatusClosed, period.Availability(at("2023-10-31T18:00:00Z")).Status)
+	})
+
+	t.Run("weekly", func(t *testing.T) {
+		// 2023-10-02 is Monday
+		assert.True(t, weekly.Availability(at("2023-10-02T10:00:00Z")).Open())
+		assert.True(t, weekly.Availability(at("2023-10-06T23:00:00Z")).Open()) // friday night
+		assert.True(t, weekly.Availability(at("2023-10-07T01:00:00Z")).Open()) // saturday morning
+
+		a := weekly.Availability(at("2023-10-02T18:00:00Z"))
+		assert.Equal(t, schema.StatusUpcoming, a.Status)
+		require.NotNil(t, a.NextOpen)
+		assert.Equal(t, at("2023-10-04T09:00:00Z"), *a.NextOpen)
+
+		a = weekly.Availability(at("2023-10-07T03:00:00Z"))
+		require.NotNil(t, a.NextOpen)
+		assert.Equal(t, at("2023-10-09T09:00:00Z"), *a.NextOpen)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := schema.FormsFromStream(strings.NewReader("schedule: {windows: [{days: [funday]}]}"))
+		assert.ErrorIs(t, err, schema.ErrInvalidWeekday)
+		_, err = schema.FormsFromStream(strings.NewReader("schedule: {windows: [{from: '25:00'}]}"))
+		assert.ErrorIs(t, err, schema.ErrInvalidClock)
+	})
+}
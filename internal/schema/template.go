@@ -64,8 +64,10 @@ type RequestContext struct {
 	Headers     http.Header
 	Query       url.Values
 	Form        url.Values
-	Code        string       // access code
-	Credentials *Credentials // optional user credentials
+	Code        string            // access code
+	CodeValues  map[string]string // values bound to issued access code
+	Signed      url.Values        // verified values from signed link
+	Credentials *Credentials      // optional user credentials
 }
 
 func (rc *RequestContext) User() string {
@@ -82,6 +84,14 @@ func (rc *RequestContext) Groups() []string {
 	return rc.Credentials.Groups
 }
 
+// Claims returns all OIDC claims. Empty for other authentication methods.
+func (rc *RequestContext) Claims() map[string]any {
+	if rc.Credentials == nil {
+		return nil
+	}
+	return rc.Credentials.Claims
+}
+
 func (rc *RequestContext) Email() string {
 	if rc.Credentials == nil {
 		return ""
@@ -91,13 +101,30 @@ func (rc *RequestContext) Email() string {
 
 // ResultContext is used for rendering result message (success or fail).
 type ResultContext struct {
-	Form   *Form
-	Result map[string]any
-	Error  error
+	Form       *Form
+	Result     map[string]any
+	Error      error
+	Submission string // unique submission ID, set only for successful submissions
 }
 
 // NotifyContext is used for rendering notification message.
 type NotifyContext struct {
-	Form   *Form
-	Result map[string]any
+	Form       *Form
+	Result     map[string]any
+	Submission string // unique submission ID
+}
+
+// FormName returns form name or empty string if form is not set.
+func (nc *NotifyContext) FormName() string {
+	if nc.Form == nil {
+		return ""
+	}
+	return nc.Form.Name
+}
+
+// LimitContext is used for rendering message when submission limit reached.
+type LimitContext struct {
+	Form  *Form
+	Limit string // reached limit: total, user, code, ip
+	Max   int    // maximum number of submissions for the limit
 }
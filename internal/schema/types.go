@@ -1,17 +1,21 @@
 package schema
 
 import (
-	"log/slog"
+	htmltemplate "html/template"
 	"time"
 
 	"github.com/google/cel-go/cel"
-	"github.com/reddec/web-form/internal/utils"
+	"github.com/reddec/web-form/internal/ratelimit"
+	"gopkg.in/yaml.v3"
 )
 
 func Default() Form {
 	return Form{
 		Success: MustTemplate[ResultContext]("Thank you for the submission!"),
 		Failed:  MustTemplate[ResultContext]("Something went wrong: `{{.Error}}`"),
+		Limits: Limits{
+			Message: MustTemplate[LimitContext](`{{if eq .Limit "total"}}Form is full{{else}}Submissions limit reached{{end}}`),
+		},
 	}
 }
 
@@ -28,31 +32,50 @@ type Form struct {
 	Success     Template[ResultContext]  // markdown message for success (also go template with available .Result)
 	Failed      Template[ResultContext]  // markdown message for failed (also go template with .Error)
 	Policy      *Policy                  // optional access policy
-	Codes       utils.Set[string]        // optional access tokens
+	RequireAuth bool                     `yaml:"require_auth"` // deny anonymous requests
+	Codes       AccessCodes              // optional access codes: plain, hashed, from environment or file
+	IssuedCodes bool                     `yaml:"issued_codes"` // accept access codes issued via CLI/API (requires database)
+	APIKeys     APIKeys                  `yaml:"api_keys"`     // optional API keys for machine clients
+	Hooks       Hooks                    // optional synchronous hooks
+	Limits      Limits                   // optional submission limits
+	RateLimit   ratelimit.Rate           `yaml:"rate_limit"` // optional rate of submissions per client, ex: 10/1m
+	OpensAt     *time.Time               `yaml:"opens_at"`   // optional time when form opens
+	ClosesAt    *time.Time               `yaml:"closes_at"`  // optional time when form closes
+	Schedule    Schedule                 // optional recurring weekly windows when form is open
+	Captcha     string                   // optional captcha name or none, by default all configured captchas are used
+	Locale      string                   // optional fixed UI language, by default selected by Accept-Language header
+	I18n        map[string]FormLocale    // optional translations of the form by language
+	Theme       Theme                    // optional custom templates and styles
 }
 
-// IsAllowed checks permission for the provided credentials.
-// Always allowed for nil policy or for nil creds, and always prohibited if policy returns non-boolean value.
+// IsAllowed checks permission for the provided credentials without request details. See Allows.
 func (f *Form) IsAllowed(creds *Credentials) bool {
-	if f.Policy == nil || creds == nil {
-		return true
-	}
-	out, _, err := f.Policy.Eval(map[string]any{
-		"user":   creds.User,
-		"email":  creds.Email,
-		"groups": creds.Groups,
-	})
-	if err != nil {
-		slog.Error("failed evaluate policy", "error", err)
-		return false
-	}
-
-	v, ok := out.ConvertToType(cel.BoolType).Value().(bool)
-	return v && ok
+	return f.Allows(&PolicyContext{Credentials: creds})
 }
 
+// CaptchaNone disables captcha for the form.
+const CaptchaNone = "none"
+
 func (f *Form) HasCodeAccess() bool {
-	return len(f.Codes) > 0
+	return len(f.Codes) > 0 || f.IssuedCodes
+}
+
+// Theme customizes look of the form pages.
+type Theme struct {
+	Layout      string            // base template for form pages (from views), default is form_base.gohtml
+	Views       map[string]string // page templates (from views) by page name (form, success, failed, ...)
+	CSS         string            // inline CSS added to form pages
+	Stylesheets []string          // URLs of additional stylesheets, for example /assets/brand.css
+}
+
+// Custom returns true if form uses own templates.
+func (t *Theme) Custom() bool {
+	return t.Layout != "" || len(t.Views) > 0
+}
+
+// Style is inline CSS. Theme is defined by administrator, so content is trusted.
+func (t *Theme) Style() htmltemplate.CSS {
+	return htmltemplate.CSS(t.CSS) //nolint:gosec
 }
 
 type Type string
@@ -91,6 +114,7 @@ type Field struct {
 	Multiple    bool                     // allow picking multiple options. Column type in database MUST be ARRAY of corresponding type.
 	Multiline   bool                     // multiline input (for [TypeString] only)
 	Icon        string                   // optional MDI icon
+	I18n        map[string]FieldLocale   // optional translations of the field by language
 }
 
 type Webhook struct {
@@ -116,13 +140,79 @@ type AMQP struct {
 	Message     Template[NotifyContext] // payload content, if not set - JSON representation of storage result
 }
 
+type Hooks struct {
+	BeforeStore *Hook `yaml:"before_store"` // called after parsing and before storing the submission
+}
+
+type Hook struct {
+	URL     string            // URL for hook, payload is JSON with form name and parsed values.
+	Method  string            // HTTP method to perform, default is POST
+	Timeout time.Duration     // request timeout
+	Headers map[string]string // arbitrary headers (ex: Authorization)
+	OnError HookPolicy        `yaml:"on_error"` // what to do if hook failed or timed out, default is block
+}
+
+type HookPolicy string
+
+const (
+	HookBlock HookPolicy = "block" // default, reject submission if hook is not available
+	HookAllow HookPolicy = "allow" // accept submission as-is if hook is not available
+)
+
+func (hp HookPolicy) Allow() bool {
+	return hp == HookAllow
+}
+
+type Limits struct {
+	Total   Limit                  // maximum number of submissions
+	PerUser Limit                  `yaml:"per_user"` // maximum number of submissions per OIDC user
+	PerCode Limit                  `yaml:"per_code"` // maximum number of submissions per access code
+	PerIP   Limit                  `yaml:"per_ip"`   // maximum number of submissions per client IP
+	Window  time.Duration          // default sliding time window for limits without own window, zero means forever
+	Message Template[LimitContext] // message shown when limit reached
+}
+
+// Limit of submissions. In YAML could be number (ex: 3) or mapping with own window (ex: {count: 3, window: 1h}).
+type Limit struct {
+	Count  int           // maximum number of submissions, zero means no limit
+	Window time.Duration // sliding time window, if not set - window of limits is used
+}
+
+func (l *Limit) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = Limit{}
+		return value.Decode(&l.Count)
+	}
+	type plain Limit
+	return value.Decode((*plain)(l))
+}
+
+// WindowOr returns own window of the limit or fallback if not set.
+func (l Limit) WindowOr(fallback time.Duration) time.Duration {
+	if l.Window > 0 {
+		return l.Window
+	}
+	return fallback
+}
+
 type Option struct {
 	Label string // label for UI
 	Value string // if not set - Label is used, allowed value should match textual representation of form value
 }
 
 type Credentials struct {
-	User   string
-	Groups []string
-	Email  string
+	User     string
+	Groups   []string
+	Email    string
+	Service  bool           // machine client authenticated by API key
+	Claims   map[string]any // all OIDC claims (if any)
+	Provider string         // name of OIDC provider (if any)
+}
+
+// GetUser returns user name or empty string for nil credentials.
+func (c *Credentials) GetUser() string {
+	if c == nil {
+		return ""
+	}
+	return c.User
 }
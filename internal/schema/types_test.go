@@ -3,8 +3,10 @@ package schema_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/reddec/web-form/internal/schema"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -94,6 +96,21 @@ policy: 'email.endsWith("@reddec.net")'
 		require.False(t, form.IsAllowed(creds))
 	})
 
+	t.Run("claims", func(t *testing.T) {
+		const txt = `
+policy: '"department" in claims && claims.department == "R&D"'
+`
+
+		f, err := schema.FormsFromStream(strings.NewReader(txt))
+		require.NoError(t, err)
+		require.NotEmpty(t, f)
+		form := f[0]
+
+		require.True(t, form.IsAllowed(&schema.Credentials{User: "admin", Claims: map[string]any{"department": "R&D"}}))
+		require.False(t, form.IsAllowed(&schema.Credentials{User: "admin", Claims: map[string]any{"department": "HR"}}))
+		require.False(t, form.IsAllowed(&schema.Credentials{User: "admin"}))
+	})
+
 	t.Run("incorrect policy", func(t *testing.T) {
 		const txt = `
 policy: '"admin" ins groups'
@@ -141,3 +158,21 @@ policy: 1
 		require.True(t, form.IsAllowed(creds))
 	})
 }
+
+func TestLimit_UnmarshalYAML(t *testing.T) {
+	const txt = `
+limits:
+  total: 30
+  per_ip:
+    count: 3
+    window: 1h
+  window: 24h
+`
+	f, err := schema.FormsFromStream(strings.NewReader(txt))
+	require.NoError(t, err)
+	limits := f[0].Limits
+	assert.Equal(t, schema.Limit{Count: 30}, limits.Total)
+	assert.Equal(t, schema.Limit{Count: 3, Window: time.Hour}, limits.PerIP)
+	assert.Equal(t, 24*time.Hour, limits.Total.WindowOr(limits.Window))
+	assert.Equal(t, time.Hour, limits.PerIP.WindowOr(limits.Window))
+}
@@ -0,0 +1,67 @@
+package signed
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/reddec/web-form/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxBodySize = 1 << 20 // maximum size of request payload
+
+// Request to sign link.
+type Request struct {
+	Values    map[string]string `json:"values"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"` // optional, default TTL used if not set
+}
+
+// Link signed by server.
+type Link struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// URL of the form with signed values. If base URL is empty, path is returned.
+func URL(base string, form string, query url.Values) string {
+	return strings.TrimRight(base, "/") + "/forms/" + url.PathEscape(form) + "?" + query.Encode()
+}
+
+// Handler signs links by POST request. Expects form name as {form} chi URL parameter.
+// Only forms from the provided set are allowed.
+func Handler(signer *Signer, forms utils.Set[string], base string, ttl time.Duration) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		form := chi.URLParam(request, "form")
+		if !forms.Has(form) {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var payload Request
+		if err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, maxBodySize)).Decode(&payload); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		expiresAt := time.Now().Add(ttl)
+		if payload.ExpiresAt != nil {
+			expiresAt = *payload.ExpiresAt
+		}
+		if !expiresAt.After(time.Now()) {
+			http.Error(writer, "expiration time in the past", http.StatusBadRequest)
+			return
+		}
+
+		values := make(url.Values, len(payload.Values))
+		for k, v := range payload.Values {
+			values.Set(k, v)
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(Link{
+			URL:       URL(base, form, signer.Sign(form, values, expiresAt)),
+			ExpiresAt: time.Unix(expiresAt.Unix(), 0),
+		})
+	}
+}
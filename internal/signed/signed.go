@@ -0,0 +1,91 @@
+// Package signed provides prefill links signed by server key.
+//
+// Signed link contains prefill values as usual query params, expiration time and HMAC-SHA256 signature of
+// form name, expiration time and all other query params. Any change of the params invalidates the link.
+package signed
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	ParamExpires   = "_expires"   // unix time (seconds) of link expiration
+	ParamSignature = "_signature" // base64 (URL) encoded HMAC-SHA256
+	MinKeySize     = 16           // minimal size of signing key in bytes
+)
+
+var (
+	ErrNotSigned = errors.New("link is not signed")
+	ErrInvalid   = errors.New("invalid link signature")
+	ErrExpired   = errors.New("link expired")
+	ErrShortKey  = errors.New("signing key is too short")
+)
+
+// New signer. Key should be at least MinKeySize bytes.
+func New(key []byte) (*Signer, error) {
+	if len(key) < MinKeySize {
+		return nil, ErrShortKey
+	}
+	return &Signer{key: key}, nil
+}
+
+type Signer struct {
+	key []byte
+}
+
+// Sign prefill values for the form. Returns query params including expiration and signature.
+func (s *Signer) Sign(form string, values url.Values, expiresAt time.Time) url.Values {
+	query := make(url.Values, len(values)+2)
+	for k, v := range values {
+		query[k] = append([]string(nil), v...)
+	}
+	query.Set(ParamExpires, strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Del(ParamSignature)
+	query.Set(ParamSignature, base64.RawURLEncoding.EncodeToString(s.mac(form, query)))
+	return query
+}
+
+// Verify signed query and returns prefill values (without expiration and signature).
+// Returns ErrNotSigned if there is no signature in query.
+func (s *Signer) Verify(form string, query url.Values, now time.Time) (url.Values, error) {
+	signature := query.Get(ParamSignature)
+	if signature == "" {
+		return nil, ErrNotSigned
+	}
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	values := make(url.Values, len(query))
+	for k, v := range query {
+		if k != ParamSignature {
+			values[k] = v
+		}
+	}
+	if !hmac.Equal(expected, s.mac(form, values)) {
+		return nil, ErrInvalid
+	}
+	expires, err := strconv.ParseInt(values.Get(ParamExpires), 10, 64)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if !now.Before(time.Unix(expires, 0)) {
+		return nil, ErrExpired
+	}
+	values.Del(ParamExpires)
+	return values, nil
+}
+
+func (s *Signer) mac(form string, query url.Values) []byte {
+	h := hmac.New(sha256.New, s.key)
+	_, _ = h.Write([]byte(form))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(query.Encode())) // sorted by key
+	return h.Sum(nil)
+}
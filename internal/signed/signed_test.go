@@ -0,0 +1,57 @@
+package signed_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/reddec/web-form/internal/signed"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	_, err := signed.New([]byte("short"))
+	require.ErrorIs(t, err, signed.ErrShortKey)
+
+	signer, err := signed.New([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+
+	now := time.Now()
+	values := url.Values{"email": {"alice@example.com"}, "tags": {"a", "b"}}
+	query := signer.Sign("invite", values, now.Add(time.Hour))
+
+	verified, err := signer.Verify("invite", query, now)
+	require.NoError(t, err)
+	assert.Equal(t, values, verified)
+
+	t.Run("tampered", func(t *testing.T) {
+		tampered, _ := url.ParseQuery(query.Encode())
+		tampered.Set("email", "mallory@example.com")
+		_, err := signer.Verify("invite", tampered, now)
+		assert.ErrorIs(t, err, signed.ErrInvalid)
+	})
+
+	t.Run("extra param", func(t *testing.T) {
+		tampered, _ := url.ParseQuery(query.Encode())
+		tampered.Set("admin", "true")
+		_, err := signer.Verify("invite", tampered, now)
+		assert.ErrorIs(t, err, signed.ErrInvalid)
+	})
+
+	t.Run("other form", func(t *testing.T) {
+		_, err := signer.Verify("other", query, now)
+		assert.ErrorIs(t, err, signed.ErrInvalid)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := signer.Verify("invite", query, now.Add(2*time.Hour))
+		assert.ErrorIs(t, err, signed.ErrExpired)
+	})
+
+	t.Run("not signed", func(t *testing.T) {
+		_, err := signer.Verify("invite", values, now)
+		assert.ErrorIs(t, err, signed.ErrNotSigned)
+	})
+}
@@ -27,6 +27,11 @@ type DBStore interface {
 	ClosableStorage
 	Exec(ctx context.Context, query string) error
 	Migrate(ctx context.Context, sourceDir string) error
+	// Ping checks database connectivity.
+	Ping(ctx context.Context) error
+	// DB returns generic database handle for internal sub-systems (ex: delivery log).
+	// Queries should use '?' placeholders and [sqlx.DB.Rebind].
+	DB() *sqlx.DB
 }
 
 func NewDB(ctx context.Context, dialect string, dbURL string) (DBStore, error) {
@@ -36,7 +41,8 @@ func NewDB(ctx context.Context, dialect string, dbURL string) (DBStore, error) {
 		if err != nil {
 			return nil, fmt.Errorf("create pool: %w", err)
 		}
-		return &pgStore{pool: pool}, nil
+		db := sqlx.NewDb(stdlib.OpenDBFromPool(pool), "pgx") // share connections with pool
+		return &pgStore{pool: pool, db: db}, nil
 	case "sqlite", "sqlite3", "lite", "file", ":memory:", "":
 		db, err := sqlx.Open("sqlite", dbURL)
 		if err != nil {
@@ -50,6 +56,7 @@ func NewDB(ctx context.Context, dialect string, dbURL string) (DBStore, error) {
 
 type pgStore struct {
 	pool *pgxpool.Pool
+	db   *sqlx.DB
 }
 
 func (s *pgStore) Store(ctx context.Context, table string, fields map[string]any) (map[string]any, error) {
@@ -111,16 +118,22 @@ func (s *pgStore) Exec(ctx context.Context, query string) error {
 }
 
 func (s *pgStore) Migrate(ctx context.Context, sourceDir string) error {
-	cfg := s.pool.Config().ConnConfig
-	db := stdlib.OpenDB(*cfg)
-	defer db.Close()
-	_, err := migrate.ExecContext(ctx, db, "postgres", migrate.FileMigrationSource{Dir: sourceDir}, migrate.Up)
+	_, err := migrate.ExecContext(ctx, s.db.DB, "postgres", migrate.FileMigrationSource{Dir: sourceDir}, migrate.Up)
 	return err
 }
 
+func (s *pgStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+func (s *pgStore) DB() *sqlx.DB {
+	return s.db
+}
+
 func (s *pgStore) Close() error {
+	err := s.db.Close() // releases connections to pool
 	s.pool.Close()
-	return nil
+	return err
 }
 
 type liteStore struct {
@@ -181,6 +194,14 @@ func (s *liteStore) Exec(ctx context.Context, query string) error {
 	return err
 }
 
+func (s *liteStore) Ping(ctx context.Context) error {
+	return s.pool.PingContext(ctx)
+}
+
+func (s *liteStore) DB() *sqlx.DB {
+	return s.pool
+}
+
 func (s *liteStore) Close() error {
 	return s.pool.Close()
 }
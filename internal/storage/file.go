@@ -48,6 +48,19 @@ func (fs *FileStore) Store(_ context.Context, table string, fields map[string]an
 	return data, atomicWrite(p, document)
 }
 
+// Ping checks that root directory is writable.
+func (fs *FileStore) Ping(context.Context) error {
+	if err := os.MkdirAll(fs.directory, 0750); err != nil {
+		return fmt.Errorf("create root dir %q: %w", fs.directory, err)
+	}
+	f, err := os.CreateTemp(fs.directory, ".ping.*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
 func atomicWrite(file string, content []byte) error {
 	d := filepath.Dir(file)
 	n := filepath.Base(file)
@@ -10,6 +10,11 @@ type ClosableStorage interface {
 	Store(ctx context.Context, table string, fields map[string]any) (map[string]any, error)
 }
 
+// Pinger is optional interface for storages which support health check.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 func NopCloser(storage interface {
 	Store(ctx context.Context, table string, fields map[string]any) (map[string]any, error)
 }) ClosableStorage {
@@ -26,6 +31,14 @@ func (fc fakeCloser) Close() error {
 	return nil
 }
 
+// Ping wrapped storage if it supports health check.
+func (fc fakeCloser) Ping(ctx context.Context) error {
+	if p, ok := fc.wrap.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
 func (fc fakeCloser) Store(ctx context.Context, table string, fields map[string]any) (map[string]any, error) {
 	return fc.wrap.Store(ctx, table, fields)
 }
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/reddec/web-form"
+
+type Config struct {
+	Endpoint string  `long:"endpoint" env:"ENDPOINT" description:"OTLP HTTP endpoint (host:port). If not set - tracing is disabled"`
+	Insecure bool    `long:"insecure" env:"INSECURE" description:"Use plain HTTP instead of HTTPS for OTLP endpoint"`
+	Service  string  `long:"service" env:"SERVICE" description:"Service name in traces" default:"web-form"`
+	Ratio    float64 `long:"ratio" env:"RATIO" description:"Sampling ratio for new traces (0..1)" default:"1"`
+}
+
+// Enabled returns true if exporter is configured.
+func (cfg *Config) Enabled() bool {
+	return cfg.Endpoint != ""
+}
+
+// Setup global tracer provider with OTLP (HTTP) exporter and W3C trace-context propagation.
+// Returned function should be called to flush pending spans.
+// If endpoint is not set, only propagation is configured and spans are not recorded.
+func Setup(ctx context.Context, cfg Config) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled() {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	var options = []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.Service)))
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer for internal spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Start new internal span.
+func Start(ctx context.Context, name string, attrs ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, attrs...)
+}
+
+// Fail marks span as failed if error is not nil.
+func Fail(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// Inject trace context into carrier (ex: HTTP headers).
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
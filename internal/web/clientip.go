@@ -0,0 +1,106 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// Proxies resolves client IP for requests coming through trusted reverse proxies.
+type Proxies struct {
+	trusted []netip.Prefix
+}
+
+// NewProxies by list of trusted proxy IPs or CIDRs.
+func NewProxies(trusted []string) (*Proxies, error) {
+	var prefixes = make([]netip.Prefix, 0, len(trusted))
+	for _, value := range trusted {
+		prefix, err := ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse trusted proxy %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return &Proxies{trusted: prefixes}, nil
+}
+
+// Handler saves resolved client IP in request context. See GetClientIP.
+func (p *Proxies) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := context.WithValue(request.Context(), clientIPKey{}, p.ClientIP(request))
+		next.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
+// ClientIP returns remote address, unless request came from trusted proxy. In that case X-Forwarded-For is checked
+// from right to left and the first hop which is not a trusted proxy is used.
+func (p *Proxies) ClientIP(request *http.Request) string {
+	client := remoteHost(request)
+	if !p.Trusted(client) {
+		return client
+	}
+	var hops []string
+	for _, header := range request.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// malformed chain - stop on the last known hop
+			break
+		}
+		client = hop
+		if !p.Trusted(hop) {
+			break
+		}
+	}
+	return client
+}
+
+// Trusted checks that IP belongs to trusted proxies.
+func (p *Proxies) Trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePrefix parses CIDR or single IP.
+func ParsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// GetClientIP returns client IP resolved by [Proxies.Handler], or remote address if request didn't pass it.
+func GetClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+func remoteHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
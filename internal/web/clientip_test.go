@@ -0,0 +1,59 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddec/web-form/internal/web"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxies_ClientIP(t *testing.T) {
+	proxies, err := web.NewProxies([]string{"10.0.0.0/8", "::1"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		Name   string
+		Remote string
+		XFF    []string
+		IP     string
+	}{
+		{Name: "direct", Remote: "192.0.2.1:1234", IP: "192.0.2.1"},
+		{Name: "spoofed by untrusted", Remote: "192.0.2.1:1234", XFF: []string{"203.0.113.7"}, IP: "192.0.2.1"},
+		{Name: "trusted proxy", Remote: "10.0.0.1:1234", XFF: []string{"203.0.113.7"}, IP: "203.0.113.7"},
+		{Name: "right-most untrusted", Remote: "10.0.0.1:1234", XFF: []string{"1.1.1.1, 203.0.113.7, 10.0.0.2"}, IP: "203.0.113.7"},
+		{Name: "multiple headers", Remote: "[::1]:1234", XFF: []string{"1.1.1.1", "203.0.113.7"}, IP: "203.0.113.7"},
+		{Name: "all trusted", Remote: "10.0.0.1:1234", XFF: []string{"10.0.0.3, 10.0.0.2"}, IP: "10.0.0.3"},
+		{Name: "malformed", Remote: "10.0.0.1:1234", XFF: []string{"203.0.113.7, garbage"}, IP: "10.0.0.1"},
+		{Name: "no header", Remote: "10.0.0.1:1234", IP: "10.0.0.1"},
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = c.Remote
+			for _, v := range c.XFF {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			assert.Equal(t, c.IP, proxies.ClientIP(req))
+
+			var resolved string
+			proxies.Handler(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				resolved = web.GetClientIP(request)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, c.IP, resolved)
+		})
+	}
+}
+
+func TestGetClientIP_withoutProxies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	assert.Equal(t, "192.0.2.1", web.GetClientIP(req))
+
+	_, err := web.NewProxies([]string{"not-an-ip"})
+	assert.Error(t, err)
+}
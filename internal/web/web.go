@@ -14,13 +14,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/reddec/web-form/internal/i18n"
 	"github.com/reddec/web-form/internal/schema"
+	"github.com/reddec/web-form/internal/tracing"
 	"github.com/reddec/web-form/internal/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	FormXSRF   = "_xsrf"
 	CookieXSRF = "_xsrf"
+
+	sessionPrefix = "__"
 )
 
 type Captcha interface {
@@ -28,6 +35,23 @@ type Captcha interface {
 	Validate(form *http.Request) bool
 }
 
+// SpamCheck is invisible check of form submission. Unlike captcha, client should not be notified about failed check.
+type SpamCheck interface {
+	Name() string // short name for metrics and logs
+	Embed() template.HTML
+	Check(form *http.Request) bool
+}
+
+// SpamFields could be implemented by SpamCheck which embeds own fields into form.
+type SpamFields interface {
+	Fields() []string
+}
+
+// IsSessionField returns true if field name is reserved for session values.
+func IsSessionField(name string) bool {
+	return strings.HasPrefix(name, sessionPrefix)
+}
+
 func NewRequest(writer http.ResponseWriter, request *http.Request) *Request {
 	remoteIP := GetClientIP(request)
 
@@ -59,6 +83,8 @@ type Request struct {
 	session  map[string]string
 	creds    *schema.Credentials
 	captchas []Captcha
+	spam     []SpamCheck
+	locale   *i18n.Localizer
 }
 
 func (r *Request) VerifyCaptcha() bool {
@@ -66,13 +92,30 @@ func (r *Request) VerifyCaptcha() bool {
 		return true
 	}
 	for _, captcha := range r.captchas {
-		if !captcha.Validate(r.request) {
+		ctx, span := tracing.Start(r.request.Context(), "Captcha.Validate", trace.WithAttributes(attribute.String("captcha", fmt.Sprintf("%T", captcha))))
+		ok := captcha.Validate(r.request.WithContext(ctx))
+		span.SetAttributes(attribute.Bool("valid", ok))
+		span.End()
+		if !ok {
 			return false
 		}
 	}
 	return true
 }
 
+// DetectSpam returns name of the first failed spam check (POST only).
+func (r *Request) DetectSpam() (string, bool) {
+	if r.request.Method != http.MethodPost {
+		return "", false
+	}
+	for _, check := range r.spam {
+		if !check.Check(r.request) {
+			return check.Name(), true
+		}
+	}
+	return "", false
+}
+
 func (r *Request) VerifyXSRF() bool {
 	return r.request.Method != http.MethodPost || verifyXSRF(r.request)
 }
@@ -82,6 +125,27 @@ func (r *Request) WithCaptcha(captcha ...Captcha) *Request {
 	return r
 }
 
+func (r *Request) WithSpamChecks(checks ...SpamCheck) *Request {
+	r.spam = checks
+	return r
+}
+
+// WithLocalizer sets language of UI messages.
+func (r *Request) WithLocalizer(localizer *i18n.Localizer) *Request {
+	r.locale = localizer
+	return r
+}
+
+// T returns localized message by key, formatted by args (if any).
+func (r *Request) T(key string, args ...any) string {
+	return r.locale.Get(key, args...)
+}
+
+// Lang is UI language.
+func (r *Request) Lang() string {
+	return r.locale.Language()
+}
+
 func (r *Request) Request() *http.Request {
 	return r.request
 }
@@ -90,6 +154,11 @@ func (r *Request) Context() context.Context {
 	return r.request.Context()
 }
 
+// Header of response.
+func (r *Request) Header() http.Header {
+	return r.writer.Header()
+}
+
 func (r *Request) Logger() *slog.Logger {
 	return r.logger
 }
@@ -98,6 +167,14 @@ func (r *Request) Credentials() *schema.Credentials {
 	return r.creds
 }
 
+// WithCredentials replaces credentials of the request (ex: after API key authentication).
+func (r *Request) WithCredentials(creds *schema.Credentials) *Request {
+	r.creds = creds
+	r.request = r.request.WithContext(schema.WithCredentials(r.request.Context(), creds))
+	r.logger = r.logger.With("user", creds.GetUser())
+	return r
+}
+
 // Clear session.
 func (r *Request) Clear() {
 	r.session = nil
@@ -181,7 +258,7 @@ func (r *Request) EmbedXSRF() template.HTML {
 func (r *Request) EmbedSession() template.HTML {
 	var out string
 	for k, v := range r.session {
-		out += `<input type="hidden" name="__` + url.QueryEscape(k) + `" value="` + url.QueryEscape(v) + `"/>`
+		out += `<input type="hidden" name="` + sessionPrefix + url.QueryEscape(k) + `" value="` + url.QueryEscape(v) + `"/>`
 	}
 	return template.HTML(out) //nolint:gosec
 }
@@ -194,6 +271,14 @@ func (r *Request) EmbedCaptcha() template.HTML {
 	return template.HTML(out) //nolint:gosec
 }
 
+func (r *Request) EmbedSpamChecks() template.HTML {
+	var out string
+	for _, v := range r.spam {
+		out += string(v.Embed())
+	}
+	return template.HTML(out) //nolint:gosec
+}
+
 func (r *Request) Render(code int, view *template.Template) {
 	var buffer bytes.Buffer
 	err := view.Execute(&buffer, r)
@@ -212,7 +297,7 @@ func (r *Request) parseSession() map[string]string {
 
 	var session = make(map[string]string)
 	for k := range r.request.PostForm {
-		if strings.HasPrefix(k, "__") {
+		if IsSessionField(k) {
 			session[k[2:]] = r.request.PostForm.Get(k)
 		}
 	}
@@ -263,12 +348,16 @@ func verifyXSRF(req *http.Request) bool {
 	return cookie.Value == formValue && formValue != ""
 }
 
-func GetClientIP(r *http.Request) string {
-	if xForwardedFor := r.Header.Get("X-Forwarded-For"); xForwardedFor != "" {
-		parts := strings.Split(xForwardedFor, ",")
-		for _, part := range parts {
-			return strings.TrimSpace(part)
-		}
+// BearerToken from Authorization header.
+func BearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, ok && token != ""
+}
+
+// LocalRedirect returns redirect target if it is a local path, otherwise root. Prevents open redirects.
+func LocalRedirect(to string) string {
+	if !strings.HasPrefix(to, "/") || strings.HasPrefix(to, "//") || strings.HasPrefix(to, "/\\") {
+		return "/"
 	}
-	return r.RemoteAddr
+	return to
 }